@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/iamskyy111/go-rest-api/internal/api/handlers"
 	"github.com/iamskyy111/go-rest-api/internal/api/middlewares"
 	"github.com/iamskyy111/go-rest-api/internal/api/router"
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/outbox"
 	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/internal/webhooks"
 	"github.com/joho/godotenv"
 )
 
@@ -23,11 +34,29 @@ func main() {
 		return
 	}
 
-	// DB connection
-	_,err=sqlconnect.ConnectDB()
+	// DB connection - the long-lived pool of the background loops and the SQL rate-limit store (request handlers open their own)
+	db,err:=sqlconnect.ConnectDB()
 	if err != nil {
 		fmt.Println("ERROR:",err)
 	}
+	defer db.Close()
+
+	// SIGINT/SIGTERM: stop taking requests, put running jobs back into the queue, then exit
+	ctx, stop:= signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// background jobs (async imports/exports/promotions, webhook deliveries) - queued ones from before a restart are picked up again
+	handlers.RegisterJobs()
+	webhooks.Register()
+	jobs.Start(ctx, db)
+	webhooks.Start(ctx, db)
+
+	// domain events are written to the outbox with every change, the dispatcher relays them to the sinks
+	sinks := []events.Sink{events.BusSink{}, webhooks.Sink{}, handlers.ChangeFeed()}
+	if os.Getenv("OUTBOX_LOG") == "true" {
+		sinks = append(sinks, events.LogSink{})
+	}
+	outbox.Start(ctx, db, sinks...)
 
 
 	PORT := os.Getenv("API_PORT")
@@ -39,32 +68,111 @@ func main() {
 		MinVersion: tls.VersionTLS12,
 	}
 
-	router:=router.Router()
-	secureMux:= middlewares.SecurityHeaders(router)
+	// RATE_LIMIT requests per RATE_LIMIT_WINDOW (100 per 1m) for every client; imports/exports are heavier.
+	// RATE_LIMIT_STORE=sql shares the counters between replicas (memory by default)
+	// (the memory store starts its own eviction goroutine - only build the one that's used)
+	var rateLimitStore middlewares.RateLimitStore
+	if os.Getenv("RATE_LIMIT_STORE") == "sql" {
+		rateLimitStore = middlewares.NewSQLRateLimitStore(db)
+	} else {
+		rateLimitStore = middlewares.NewMemoryRateLimitStore()
+	}
+	rl:= middlewares.NewRateLimiterWithOptions(middlewares.RateLimiterOptions{
+		Default: middlewares.RateLimitPolicy{Limit: envInt("RATE_LIMIT", 100), Window: envDuration("RATE_LIMIT_WINDOW", time.Minute)},
+		Routes: []middlewares.RateLimitRoute{
+			{Method: http.MethodPost, PathPrefix: "/teachers/import", Policy: middlewares.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+			{Method: http.MethodPost, PathPrefix: "/students/import", Policy: middlewares.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+			{Method: http.MethodGet, PathPrefix: "/teachers/export", Policy: middlewares.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+			{Method: http.MethodGet, PathPrefix: "/students/export", Policy: middlewares.RateLimitPolicy{Limit: 10, Window: time.Minute}},
+			{Method: http.MethodPost, PathPrefix: "/csp-report", Policy: middlewares.RateLimitPolicy{Limit: 30, Window: time.Minute}},
+		},
+		KeyBy: os.Getenv("RATE_LIMIT_KEY"), // ip (default), api_key or user
+		APIKeys: strings.Split(os.Getenv("API_KEYS"), ","), // the X-API-Keys handed out - other keys count as the IP
+		TrustedProxies: strings.Split(os.Getenv("TRUSTED_PROXIES"), ","),
+		Store: rateLimitStore,
+	})
+
+	// CORS policy from CORS_CONFIG (JSON file) or the CORS_* env vars
+	corsOptions, err:= middlewares.LoadCORSOptions()
+	if err != nil {
+		log.Fatal("⚠️ERROR. loading the CORS config:",err)
+	}
+
+	// repeated parameters: HPP_POLICY first (default), last or reject - sortby and include are lists by design
+	hppOptions:= middlewares.HPPOptions{
+		CheckQuery: true,
+		CheckBody: true,
+		CheckBodyOnlyForContentType: "application/x-www-form-urlencoded",
+		CheckJSONBody: true,
+		WhiteList: []string{"sortby","include"},
+		Policy: os.Getenv("HPP_POLICY"),
+	}
+
+	// security headers - violations of the CSP are reported to (and logged by) POST /csp-report
+	securityPolicy:= middlewares.DefaultSecurityPolicy()
+	securityPolicy.CSP.ReportURI = "/csp-report"
+	if os.Getenv("CSP_REPORT_ONLY") == "true" {
+		securityPolicy.CSP.ReportOnly = true
+	}
+
+	// the server-wide chain, outermost first - groups and routes add theirs inside it (auth), or skip some (compression on streams).
+	// CORS answers preflights before they count against the rate-limit, and its headers are on 429s too;
+	// content-negotiation sits right around the handlers, so every other mw sees the final encoding
+	mux:= router.Router(
+		router.MW("security-headers", middlewares.SecurityHeadersWithPolicy(securityPolicy)),
+		router.MW("cors", middlewares.CorsMiddlewareWithOptions(corsOptions)),
+		router.MW("rate-limit", rl.RateLimiterMiddleware),
+		router.MW("compression", middlewares.CompressionMiddleware),
+		router.MW("hpp", middlewares.HppMiddleware(hppOptions)),
+		router.MW("content-negotiation", middlewares.ContentNegotiationMiddleware),
+	)
+	// ROUTES_DEBUG=true prints every route with its effective chain
+	if os.Getenv("ROUTES_DEBUG") == "true" {
+		mux.Dump(os.Stdout)
+	}
 
 	// Create custom-server
+	// long-lived responses (GET /events/stream, big exports) move their own write deadline
 	server:= &http.Server{
 		Addr:PORT,
-		Handler: secureMux,
+		Handler: mux,
 		TLSConfig: tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout: 30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout: 120 * time.Second,
 	}
+	// open requests get 30s to finish (streams are cut off then)
+	stopped:= make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		fmt.Println("Shutting down... ⏳")
+		shutdownCtx, cancel:= context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
 	fmt.Println("Server is running on PORT", PORT,"🟢")
 	err= server.ListenAndServeTLS(cert,key)
-	if err!=nil{
+	if err!=nil && err!=http.ErrServerClosed{
 		log.Fatal("⚠️ERROR. starting the server:",err)
 	}
+	<-stopped
+	jobs.Wait()
+	fmt.Println("Server stopped 🔴")
 }
 
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
 
-// initialize the rate-limiter ✅
-	//rl:= middlewares.NewRateLimiter(5, time.Minute)
-
-	// instance of the HppOptions struct ✅
-	// hppOptions:= middlewares.HPPOptions{
-	// 	CheckQuery: true,
-	// 	CheckBody: true,
-	// 	CheckBodyOnlyForContentType: "application/x-www-form-urlencoded",
-	// 	WhiteList: []string{"sortBy","sortOrder","name","age","class"},
-	// }
-
-	//secureMux:= utils.ApplyMiddlewares(mux, middlewares.HppMiddleware(hppOptions), middlewares.CompressionMiddleware, middlewares.CompressionMiddleware, middlewares.SecurityHeaders, middlewares.ResponseTimeMiddleware, rl.RateLimiterMiddleware,middlewares.CorsMiddleware)
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
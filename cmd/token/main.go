@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
+	"github.com/joho/godotenv"
+)
+
+// Issues an access token with the server's JWT_SECRET - how the first exec gets one:
+//
+//	go run ./cmd/token -role exec -id 1 -ttl 24h
+//
+// With it, execs hand out tokens to teachers (and other execs) through POST /auth/tokens.
+func main() {
+	role := flag.String("role", auth.RoleExec, "exec or teacher")
+	id := flag.Int("id", 0, "exec/teacher-ID")
+	ttl := flag.Duration("ttl", auth.DefaultTTL, "how long the token is valid")
+	flag.Parse()
+
+	// the .env of the server, if there is one - JWT_SECRET may come from the environment as well
+	godotenv.Load()
+
+	if (*role != auth.RoleExec && *role != auth.RoleTeacher) || *id < 1 || *ttl <= 0 || *ttl > auth.MaxTTL {
+		fmt.Fprintf(os.Stderr, "usage: token -role exec|teacher -id <ID> [-ttl <= %s] ⚠️\n", auth.MaxTTL)
+		os.Exit(2)
+	}
+	token, err := auth.Issue(auth.Principal{Role: *role, ID: *id}, *ttl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+	fmt.Println(token)
+}
@@ -3,6 +3,7 @@ module github.com/iamskyy111/go-rest-api
 go 1.24.4
 
 require (
+	github.com/andybalholm/brotli v1.2.6
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/joho/godotenv v1.5.1
 )
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// validateDateRange checks two YYYY-MM-DD dates, start <= end
+func validateDateRange(start, end string) error {
+	s, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return fmt.Errorf("invalid start_date %q, expected YYYY-MM-DD ⚠️", start)
+	}
+	e, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return fmt.Errorf("invalid end_date %q, expected YYYY-MM-DD ⚠️", end)
+	}
+	if e.Before(s) {
+		return fmt.Errorf("end_date is before start_date ⚠️")
+	}
+	return nil
+}
+
+//! 1️⃣☑️ GET/FETCH academic years
+func GetAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	years, err := sqlconnect.GetAcademicYearsDbHandler()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string                `json:"status"`
+		Count  int                   `json:"count"`
+		Data   []models.AcademicYear `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(years),
+		Data:   years,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single academic year /id
+func GetAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	year, err := sqlconnect.GetAcademicYearDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(year)
+}
+
+//! 3️⃣☑️ ADD/POST academic year
+func AddAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	var year models.AcademicYear
+	err := utils.DecodeBody(r, &year)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if year.Name == "" {
+		http.Error(w, "academic year name is required ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validateDateRange(year.StartDate, year.EndDate); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, err := sqlconnect.AddAcademicYearDbHandler(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+//! 4️⃣☑️ Partially-Edit/PATCH academic year /id
+func PatchAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid academic-year-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.PatchAcademicYearDbHandler(id, updates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+//! 5️⃣☑️ DELETE academic year /id
+func DeleteAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid academic-year-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteAcademicYearDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Academic year successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 6️⃣☑️ GET terms /academic-years/id/terms
+func GetTermsHandler(w http.ResponseWriter, r *http.Request) {
+	yearId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid academic-year-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	terms, err := sqlconnect.GetTermsDbHandler(yearId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeTerms(w, terms)
+}
+
+//! 7️⃣☑️ ADD/POST terms /academic-years/id/terms
+func AddTermsHandler(w http.ResponseWriter, r *http.Request) {
+	yearId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid academic-year-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var newTerms []models.Term
+	err = utils.DecodeBody(r, &newTerms)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, t := range newTerms {
+		if t.Name == "" {
+			http.Error(w, "term name is required ⚠️", http.StatusBadRequest)
+			return
+		}
+		if err := validateDateRange(t.StartDate, t.EndDate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	added, err := sqlconnect.AddTermsDbHandler(yearId, newTerms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeTerms(w, added)
+}
+
+func writeTerms(w http.ResponseWriter, terms []models.Term) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []models.Term `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(terms),
+		Data:   terms,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
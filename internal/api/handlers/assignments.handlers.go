@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// Teacher <-> Subject <-> Class assignments, always scoped to /teachers/{id}
+
+//! 1️⃣☑️ GET assignments of a teacher
+func GetTeacherAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid teacher-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	assignments, err := sqlconnect.GetTeacherAssignmentsDbHandler(teacherId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assignments),
+		Data:   assignments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ ASSIGN subject(s) for class(es) to a teacher
+// body: [{"subject_id": 1, "class_id": 4}, ...]
+func AddTeacherAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid teacher-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var newAssignments []models.Assignment
+	err = utils.DecodeBody(r, &newAssignments)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, a := range newAssignments {
+		if a.SubjectID < 1 || a.ClassID < 1 {
+			http.Error(w, "subject_id and class_id are required ⚠️", http.StatusBadRequest)
+			return
+		}
+	}
+
+	assignments, err := sqlconnect.AddTeacherAssignmentsDbHandler(teacherId, newAssignments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assignments),
+		Data:   assignments,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 3️⃣☑️ UNASSIGN a subject/class from a teacher
+func DeleteTeacherAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid teacher-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+	assignmentId, err := strconv.Atoi(r.PathValue("assignmentId"))
+	if err != nil {
+		http.Error(w, "Invalid assignment-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteTeacherAssignmentDbHandler(teacherId, assignmentId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Assignment successfully REMOVED ✅",
+		ID:     assignmentId,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+func validateAttendanceSheet(sheet models.AttendanceSheet) error {
+	if _, err := time.Parse(time.DateOnly, sheet.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD ⚠️", sheet.Date)
+	}
+	if sheet.Period < 1 {
+		return fmt.Errorf("period must be positive ⚠️")
+	}
+	// the teacher-check of the class runs against marked_by - without it anybody could mark any class
+	if sheet.MarkedBy < 1 {
+		return fmt.Errorf("marked_by (teacher-ID) is required ⚠️")
+	}
+	if len(sheet.Records) == 0 {
+		return fmt.Errorf("no attendance records ⚠️")
+	}
+	seen := map[int]bool{}
+	for _, record := range sheet.Records {
+		if record.StudentID < 1 {
+			return fmt.Errorf("student_id is required ⚠️")
+		}
+		if seen[record.StudentID] {
+			return fmt.Errorf("student %d is marked twice ⚠️", record.StudentID)
+		}
+		seen[record.StudentID] = true
+		if !sqlconnect.IsValidAttendanceStatus(record.Status) {
+			return fmt.Errorf("invalid status %q for student %d ⚠️", record.Status, record.StudentID)
+		}
+	}
+	return nil
+}
+
+//! 1️⃣☑️ Bulk-MARK attendance for a class /classes/id/attendance
+func MarkClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var sheet models.AttendanceSheet
+	err = utils.DecodeBody(r, &sheet)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if sheet.Period == 0 {
+		sheet.Period = 1 // single-period schools don't have to send it
+	}
+	// a teacher marks as themself, execs name the teacher they mark for
+	if principal, ok := auth.FromContext(r.Context()); ok && principal.Role == auth.RoleTeacher {
+		sheet.MarkedBy = principal.ID
+	}
+	if err := validateAttendanceSheet(sheet); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	marked, err := sqlconnect.MarkClassAttendanceDbHandler(classId, sheet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the classes' live rooms see REST marks as well
+	broadcastMarked(classId, sheet.Date, sheet.Period, marked, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeAttendance(w, marked)
+}
+
+//! 2️⃣☑️ GET attendance of a class /classes/id/attendance?date=&period=
+func GetClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	records, err := sqlconnect.GetClassAttendanceDbHandler(classId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeAttendance(w, records)
+}
+
+//! 3️⃣☑️ GET attendance history of a student /students/id/attendance?from=&to=
+func GetStudentAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	records, err := sqlconnect.GetStudentAttendanceDbHandler(studentId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeAttendance(w, records)
+}
+
+//! 4️⃣☑️ GET absence summary of a student /students/id/attendance/summary?from=&to=
+func GetStudentAttendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := sqlconnect.GetStudentAttendanceSummaryDbHandler(studentId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string                   `json:"status"`
+		Data   models.AttendanceSummary `json:"data"`
+	}{
+		Status: "success",
+		Data:   summary,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 5️⃣☑️ GET absence summary of a class /classes/id/attendance/summary?from=&to=
+func GetClassAttendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := sqlconnect.GetClassAttendanceSummaryDbHandler(classId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string                     `json:"status"`
+		Count  int                        `json:"count"`
+		Data   []models.AttendanceSummary `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(summaries),
+		Data:   summaries,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeAttendance(w http.ResponseWriter, records []models.Attendance) {
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Attendance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(records),
+		Data:   records,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+type tokenRequest struct {
+	Role string `json:"role"`
+	ID   int    `json:"id"`
+	TTL  string `json:"ttl,omitempty"` // "12h" (default), at most 720h
+}
+
+type tokenResponse struct {
+	Token     string    `json:"token"`
+	Role      string    `json:"role"`
+	ID        int       `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+//! 1️⃣☑️ ISSUE an access token /auth/tokens - execs for anybody, teachers renew their own
+func IssueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required ⚠️", http.StatusUnauthorized)
+		return
+	}
+
+	var req tokenRequest
+	if err := utils.DecodeBody(r, &req); err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" && req.ID == 0 {
+		req.Role, req.ID = principal.Role, principal.ID
+	}
+	if req.Role != auth.RoleExec && req.Role != auth.RoleTeacher {
+		http.Error(w, fmt.Sprintf("role must be %q or %q ⚠️", auth.RoleExec, auth.RoleTeacher), http.StatusBadRequest)
+		return
+	}
+	if req.ID < 1 {
+		http.Error(w, "id is required ⚠️", http.StatusBadRequest)
+		return
+	}
+	ttl := auth.DefaultTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > auth.MaxTTL {
+			http.Error(w, fmt.Sprintf("invalid ttl %q, expected a duration up to %s ⚠️", req.TTL, auth.MaxTTL), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+
+	if principal.Role != auth.RoleExec && (req.Role != principal.Role || req.ID != principal.ID) {
+		http.Error(w, "Only execs issue tokens for others ⚠️", http.StatusForbidden)
+		return
+	}
+	if req.Role == auth.RoleTeacher {
+		if _, err := sqlconnect.GetTeacherDbHandler(req.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+
+	token, err := auth.Issue(auth.Principal{Role: req.Role, ID: req.ID}, ttl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string        `json:"status"`
+		Data   tokenResponse `json:"data"`
+	}{
+		Status: "success",
+		Data:   tokenResponse{Token: token, Role: req.Role, ID: req.ID, ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second)},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
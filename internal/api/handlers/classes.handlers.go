@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// validatePatchedClasses checks the "class" key of PATCH-maps against the classes table
+func validatePatchedClasses(updates ...map[string]any) error {
+	var classes []string
+	for _, update := range updates {
+		v, ok := update["class"]
+		if !ok {
+			continue
+		}
+		class, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("class must be a string ⚠️")
+		}
+		classes = append(classes, class)
+	}
+	return sqlconnect.ValidateClasses(classes...)
+}
+
+func validateClass(c models.Class) error {
+	if c.Name == "" {
+		return fmt.Errorf("class name is required ⚠️")
+	}
+	if c.Grade < 1 {
+		return fmt.Errorf("class grade must be positive ⚠️")
+	}
+	if c.Capacity < 0 {
+		return fmt.Errorf("class capacity cannot be negative ⚠️")
+	}
+	return nil
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH classes
+func GetClassesHandler(w http.ResponseWriter, r *http.Request) {
+	classes, err := sqlconnect.GetClassesDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(classes),
+		Data:   classes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single-class /id
+func GetClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	class, err := sqlconnect.GetClassDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(class)
+}
+
+//! 3️⃣☑️ ADD/POST Class(es)
+func AddClassesHandler(w http.ResponseWriter, r *http.Request) {
+	var newClasses []models.Class
+	err := utils.DecodeBody(r, &newClasses)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, c := range newClasses {
+		if err := validateClass(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	addedClasses, err := sqlconnect.AddClassesDbHandler(newClasses)
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrClassNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	resp := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedClasses),
+		Data:   addedClasses,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 4️⃣☑️ UPDATE/PUT Classes/id
+func UpdateClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updatedClass models.Class
+	err = utils.DecodeBody(r, &updatedClass)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validateClass(updatedClass); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedClassFromDb, err := sqlconnect.UpdateClassDbHandler(id, updatedClass)
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrClassNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrClassNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedClassFromDb)
+}
+
+//! 5️⃣☑️ Partially-Edit/PATCH Class/id
+func PatchClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	updatedClass, err := sqlconnect.PatchClassDbHandler(id, updates, validateClass)
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrClassNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrClassNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedClass)
+}
+
+//! 6️⃣☑️ DELETE Class/id
+func DeleteClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteClassDbHandler(id)
+	var inUse *sqlconnect.ClassInUseError
+	switch {
+	case errors.Is(err, sqlconnect.ErrClassNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.As(err, &inUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Class successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 7️⃣☑️ GET students of a class /id/students
+func GetClassStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	students, err := sqlconnect.GetClassStudentsDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Student `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(students),
+		Data:   students,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 8️⃣☑️ GET teachers of a class /id/teachers
+func GetClassTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	teachers, err := sqlconnect.GetClassTeachersDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Teacher `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(teachers),
+		Data:   teachers,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
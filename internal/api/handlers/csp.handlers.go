@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+const maxCSPReportSize = 64 << 10
+
+// cspViolation - the fields we log, from either report format
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	Disposition        string `json:"disposition"` // enforce, report
+}
+
+// Reporting API (application/reports+json) names the same fields in camelCase
+type reportingAPIViolation struct {
+	DocumentURL        string `json:"documentURL"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	SourceFile         string `json:"sourceFile"`
+	LineNumber         int    `json:"lineNumber"`
+	Disposition        string `json:"disposition"`
+}
+
+//! 1️⃣☑️ RECEIVE CSP violation reports /csp-report - browsers POST them here (report-uri), we log them
+// application/csp-report: {"csp-report": {...}}, application/reports+json: [{"type": "csp-violation", "body": {...}}]
+func CSPReportHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxCSPReportSize))
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	var violations []cspViolation
+	var legacy struct {
+		Report *cspViolation `json:"csp-report"`
+	}
+	var reports []struct {
+		Type string                `json:"type"`
+		Body reportingAPIViolation `json:"body"`
+	}
+	if json.Unmarshal(data, &legacy) == nil && legacy.Report != nil {
+		violations = append(violations, *legacy.Report)
+	} else if json.Unmarshal(data, &reports) == nil {
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{DocumentURI: report.Body.DocumentURL, BlockedURI: report.Body.BlockedURL,
+				EffectiveDirective: report.Body.EffectiveDirective, SourceFile: report.Body.SourceFile,
+				LineNumber: report.Body.LineNumber, Disposition: report.Body.Disposition})
+		}
+	} else {
+		http.Error(w, "Invalid CSP report ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	for _, v := range violations {
+		directive := v.EffectiveDirective
+		if directive == "" {
+			directive = v.ViolatedDirective
+		}
+		// every field comes from the (unauthenticated) client - quoted, so newlines can't forge log lines
+		log.Printf("CSP violation (%q): %q blocked %q on %q (%q:%d)", v.Disposition, directive, v.BlockedURI, v.DocumentURI, v.SourceFile, v.LineNumber)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+//! 1️⃣☑️ GET enrollment history /students/id/enrollments
+func GetStudentEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := sqlconnect.GetStudentEnrollmentsDbHandler(studentId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollments),
+		Data:   enrollments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ TRANSFER a student /students/id/transfer
+// body: {"class_id": 7, "date": "2026-11-02"}
+func TransferStudentHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TransferRequest
+	err = utils.DecodeBody(r, &req)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if req.ClassID < 1 {
+		http.Error(w, "class_id is required ⚠️", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse(time.DateOnly, req.Date); req.Date != "" && err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	student, err := sqlconnect.TransferStudentDbHandler(studentId, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(student)
+}
+
+//! 3️⃣☑️ PROMOTE a whole class /classes/id/promote
+// body: {"to_class_id": 12, "date": "2027-08-01", "academic_year_id": 3} - to_class_id defaults to grade + 1
+// Prefer: respond-async (or ?async=true) runs it as a background job -> 202 + Location: /jobs/{id}
+func PromoteClassHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PromotionRequest
+	err = utils.DecodeBody(r, &req)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse(time.DateOnly, req.Date); req.Date != "" && err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD ⚠️", http.StatusBadRequest)
+		return
+	}
+	if wantsAsync(r) {
+		job, err := jobs.Enqueue(jobClassesPromote, promotePayload{ClassID: classId, Request: req})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAccepted(w, job)
+		return
+	}
+
+	result, err := sqlconnect.PromoteClassDbHandler(classId, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   models.PromotionResult `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(result.StudentIDs),
+		Data:   result,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+)
+
+var (
+	changeFeed     *events.Feed
+	changeFeedOnce sync.Once
+)
+
+// ChangeFeed is the outbox sink the event streams read from - SSE_REPLAY_SIZE (1000) events are kept for resumption.
+// It's per process: with several replicas a stream only gets the events its own replica relayed (see events.Feed).
+// Created on first use, the .env file isn't loaded yet at init().
+func ChangeFeed() *events.Feed {
+	changeFeedOnce.Do(func() {
+		changeFeed = events.NewFeed(envInt("SSE_REPLAY_SIZE", 1000))
+	})
+	return changeFeed
+}
+
+const sseRetryMS = 3000 // how long EventSource waits before reconnecting
+
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+// sseHeartbeat - SSE_HEARTBEAT seconds (15); keeps proxies from closing an idle stream
+func sseHeartbeat() time.Duration {
+	return time.Duration(envInt("SSE_HEARTBEAT", 15)) * time.Second
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func writeSSE(w http.ResponseWriter, entry events.FeedEntry) error {
+	data, err := json.Marshal(entry.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", entry.ID, entry.Event.Type, data)
+	return err
+}
+
+//! 1️⃣☑️ STREAM change events /events/stream?entity=teacher,student&type=teacher.created,student.*
+// text/event-stream; resumes after the Last-Event-ID header (or ?last_event_id=) from the replay buffer.
+// A "reset" event means events were missed and the client should reload its data.
+func EventStreamHandler(w http.ResponseWriter, r *http.Request) {
+	entities := splitList(r.URL.Query().Get("entity"))
+	for _, entity := range entities {
+		if entity != "teacher" && entity != "student" {
+			http.Error(w, "entity must be teacher or student ⚠️", http.StatusBadRequest)
+			return
+		}
+	}
+	types := splitList(r.URL.Query().Get("type"))
+	if err := validateEventFilter(types); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wanted := func(e events.Event) bool {
+		return (len(entities) == 0 || slices.Contains(entities, e.AggregateType)) &&
+			(len(types) == 0 || events.Match(types, e.Type))
+	}
+
+	// the stream outlives the server's WriteTimeout, and every event has to leave right away
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, "streaming not supported ⚠️", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	replay, live, gap, cancel := ChangeFeed().Listen(lastEventID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMS)
+	if gap {
+		reason, _ := json.Marshal(map[string]string{"reason": "events since " + lastEventID + " are no longer available"})
+		fmt.Fprintf(w, "event: reset\ndata: %s\n\n", reason)
+	}
+	for _, entry := range replay {
+		if wanted(entry.Event) {
+			if err := writeSSE(w, entry); err != nil {
+				return
+			}
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case entry, ok := <-live:
+			if !ok {
+				return // too slow - the client reconnects and catches up from the replay buffer
+			}
+			if !wanted(entry.Event) {
+				continue
+			}
+			if err := writeSSE(w, entry); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
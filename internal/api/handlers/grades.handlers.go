@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+func validateAssessment(a models.Assessment) error {
+	if a.Title == "" || a.Term == "" {
+		return fmt.Errorf("assessment title and term are required ⚠️")
+	}
+	if !sqlconnect.IsValidAssessmentType(a.Type) {
+		return fmt.Errorf("invalid assessment type %q, expected exam, quiz or assignment ⚠️", a.Type)
+	}
+	if a.SubjectID < 1 || a.ClassID < 1 {
+		return fmt.Errorf("subject_id and class_id are required ⚠️")
+	}
+	if a.MaxScore <= 0 {
+		return fmt.Errorf("max_score must be positive ⚠️")
+	}
+	if _, err := time.Parse(time.DateOnly, a.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD ⚠️", a.Date)
+	}
+	return nil
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH assessments
+func GetAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	assessments, err := sqlconnect.GetAssessmentsDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assessments),
+		Data:   assessments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single-assessment /id
+func GetAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := sqlconnect.GetAssessmentDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessment)
+}
+
+//! 3️⃣☑️ ADD/POST Assessment(s)
+func AddAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	var newAssessments []models.Assessment
+	err := utils.DecodeBody(r, &newAssessments)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, a := range newAssessments {
+		if err := validateAssessment(a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	added, err := sqlconnect.AddAssessmentsDbHandler(newAssessments)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	resp := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(added),
+		Data:   added,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 4️⃣☑️ Partially-Edit/PATCH Assessment/id
+func PatchAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assessment-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.PatchAssessmentDbHandler(id, updates, validateAssessment)
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrAssessmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+//! 5️⃣☑️ DELETE Assessment/id
+func DeleteAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assessment-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteAssessmentDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Assessment successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 6️⃣☑️ Bulk-ENTER scores /assessments/id/scores
+// body: [{"student_id": 1, "score": 42.5, "remarks": "..."}, ...]
+func AddScoresHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assessment-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var newScores []models.Score
+	err = utils.DecodeBody(r, &newScores)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if len(newScores) == 0 {
+		http.Error(w, "no scores ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	scores, err := sqlconnect.AddScoresDbHandler(id, newScores)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeScores(w, scores)
+}
+
+//! 7️⃣☑️ GET scores /assessments/id/scores
+func GetScoresHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid assessment-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	scores, err := sqlconnect.GetScoresDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeScores(w, scores)
+}
+
+func writeScores(w http.ResponseWriter, scores []models.Score) {
+	resp := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Score `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(scores),
+		Data:   scores,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 8️⃣☑️ GET report card /students/id/report-card?term=&format=csv
+func GetReportCardHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	lines, err := sqlconnect.GetReportCardDbHandler(studentId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="report-card-student-%d.csv"`, studentId))
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"subject", "term", "assessments", "average", "grade"})
+		for _, l := range lines {
+			cw.Write([]string{l.Subject, l.Term, strconv.Itoa(l.Assessments), strconv.FormatFloat(l.Average, 'f', 2, 64), l.Grade})
+		}
+		cw.Flush()
+		return
+	}
+
+	resp := struct {
+		Status    string                  `json:"status"`
+		StudentID int                     `json:"student_id"`
+		Data      []models.ReportCardLine `json:"data"`
+	}{
+		Status:    "success",
+		StudentID: studentId,
+		Data:      lines,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 9️⃣☑️ GET grade distribution /classes/id/grade-distribution?subject_id=&term=
+func GetGradeDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := sqlconnect.GetGradeDistributionDbHandler(classId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Status  string               `json:"status"`
+		ClassID int                  `json:"class_id"`
+		Data    []models.GradeBucket `json:"data"`
+	}{
+		Status:  "success",
+		ClassID: classId,
+		Data:    buckets,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,24}$`)
+
+func validateGuardian(g models.Guardian) error {
+	if g.FirstName == "" || g.LastName == "" {
+		return fmt.Errorf("guardian first_name and last_name are required ⚠️")
+	}
+	if !sqlconnect.IsValidRelation(g.Relation) {
+		return fmt.Errorf("invalid relation %q ⚠️", g.Relation)
+	}
+	if g.Phone == "" && g.Email == "" {
+		return fmt.Errorf("guardian needs a phone or an email ⚠️")
+	}
+	return validateContact(g.Phone, g.Email)
+}
+
+func validateContact(phone, email string) error {
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return fmt.Errorf("invalid phone %q ⚠️", phone)
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("invalid email %q ⚠️", email)
+		}
+	}
+	return nil
+}
+
+// validatePatchedGuardian checks only the fields present in the PATCH body
+func validatePatchedGuardian(updates map[string]any) error {
+	if relation, ok := updates["relation"]; ok {
+		if r, _ := relation.(string); !sqlconnect.IsValidRelation(r) {
+			return fmt.Errorf("invalid relation %v ⚠️", relation)
+		}
+	}
+	for _, field := range []string{"first_name", "last_name"} {
+		if v, ok := updates[field]; ok && v == "" {
+			return fmt.Errorf("%s can't be empty ⚠️", field)
+		}
+	}
+	phone, _ := updates["phone"].(string)
+	email, _ := updates["email"].(string)
+	return validateContact(phone, email)
+}
+
+func writeGuardians(w http.ResponseWriter, guardians []models.Guardian) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardians),
+		Data:   guardians,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH guardians - ?student=ID&relation=&is_primary=true&sortby=last_name:asc
+func GetGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	guardians, err := sqlconnect.GetGuardiansDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeGuardians(w, guardians)
+}
+
+//! 2️⃣☑️ GET/FETCH single-guardian /id
+func GetGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	guardian, err := sqlconnect.GetGuardianDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guardian)
+}
+
+//! 3️⃣☑️ ADD/POST Guardian(s)
+func AddGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	var newGuardians []models.Guardian
+	err := utils.DecodeBody(r, &newGuardians)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, g := range newGuardians {
+		if err := validateGuardian(g); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	addedGuardians, err := sqlconnect.AddGuardiansDbHandler(newGuardians)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeGuardians(w, addedGuardians)
+}
+
+//! 4️⃣☑️ UPDATE/PUT Guardians/id
+func UpdateGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid guardian-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updatedGuardian models.Guardian
+	err = utils.DecodeBody(r, &updatedGuardian)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validateGuardian(updatedGuardian); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedGuardianFromDb, err := sqlconnect.UpdateGuardianDbHandler(id, updatedGuardian)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGuardianFromDb)
+}
+
+//! 5️⃣☑️ Partially-Edit/PATCH Guardian/id
+func PatchGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid guardian-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validatePatchedGuardian(updates); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedGuardian, err := sqlconnect.PatchGuardianDbHandler(id, updates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGuardian)
+}
+
+//! 6️⃣☑️ DELETE Guardian/id
+func DeleteGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid guardian-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteGuardianDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Guardian successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 7️⃣☑️ GET guardians of a student /students/id/guardians
+func GetStudentGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	guardians, err := sqlconnect.GetStudentGuardiansDbHandler(studentId, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeGuardians(w, guardians)
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+const (
+	maxImportSize  = 10 << 20        // 10MB
+	importDeadline = 5 * time.Minute // instead of the server's read/write timeouts - use Prefer: respond-async beyond that
+)
+
+// csvColumns maps the header row onto struct fields; a column may be named after the json- or the db-tag.
+// "id" and computed fields (no db-tag) are ignored, so an export can be imported back as-is.
+func csvColumns(header []string, model any) ([]int, error) {
+	modelType := reflect.TypeOf(model)
+	fields := make([]int, len(header))
+	for col, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		fields[col] = -1
+		for i := 0; i < modelType.NumField(); i++ {
+			dbTag := strings.Split(modelType.Field(i).Tag.Get("db"), ",")[0]
+			jsonTag := strings.Split(modelType.Field(i).Tag.Get("json"), ",")[0]
+			if name != dbTag && name != jsonTag {
+				continue
+			}
+			if dbTag != "" && dbTag != "id" {
+				fields[col] = i
+			}
+			break
+		}
+		if fields[col] == -1 && name != "id" && !isComputedField(modelType, name) {
+			return nil, fmt.Errorf("unknown CSV column %q ⚠️", name)
+		}
+	}
+	return fields, nil
+}
+
+func isComputedField(modelType reflect.Type, name string) bool {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name && field.Tag.Get("db") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeCSV reads a CSV with a header row into []T. A bad row doesn't stop the import,
+// its error is returned at the same index in rowErrs.
+func decodeCSV[T any](body io.Reader) ([]T, []error, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // checked per row below, so one short row doesn't abort everything
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("empty CSV, a header row is required ⚠️")
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV header: %v ⚠️", err)
+	}
+	var zero T
+	fields, err := csvColumns(header, zero)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []T
+	var rowErrs []error
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// the reader can't recover from a broken quote etc., so that fails the whole file
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return nil, nil, fmt.Errorf("invalid CSV on line %d: %v ⚠️", pe.Line, pe.Err)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %v ⚠️", err)
+		}
+
+		var row T
+		if len(record) != len(header) {
+			err = fmt.Errorf("expected %d columns, got %d ⚠️", len(header), len(record))
+		} else {
+			err = setCSVFields(&row, header, fields, record)
+		}
+		rows, rowErrs = append(rows, row), append(rowErrs, err)
+	}
+	return rows, rowErrs, nil
+}
+
+func setCSVFields(model any, header []string, fields []int, record []string) error {
+	modelVal := reflect.ValueOf(model).Elem()
+	for col, i := range fields {
+		if i < 0 {
+			continue
+		}
+		value := strings.TrimSpace(record[col])
+		field := modelVal.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int:
+			if value == "" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %q is not a number ⚠️", header[col], value)
+			}
+			field.SetInt(int64(n))
+		case reflect.Bool:
+			if value == "" {
+				continue
+			}
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("column %s: %q is not true/false ⚠️", header[col], value)
+			}
+			field.SetBool(b)
+		default:
+			return fmt.Errorf("column %s can't be imported ⚠️", header[col])
+		}
+	}
+	return nil
+}
+
+func validatePerson(firstName, lastName, email string) error {
+	if firstName == "" || lastName == "" || email == "" {
+		return fmt.Errorf("first_name, last_name and email are required ⚠️")
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return fmt.Errorf("invalid email %q ⚠️", email)
+	}
+	return nil
+}
+
+// readImport checks Content-Type and ?mode= before any row is read
+func readImport(w http.ResponseWriter, r *http.Request) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/csv" {
+		http.Error(w, "Content-Type must be text/csv ⚠️", http.StatusUnsupportedMediaType)
+		return "", false
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.ImportAtomic
+	}
+	if mode != models.ImportAtomic && mode != models.ImportBestEffort {
+		http.Error(w, "mode must be atomic or best-effort ⚠️", http.StatusBadRequest)
+		return "", false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(importDeadline))
+	rc.SetWriteDeadline(time.Now().Add(importDeadline))
+	return mode, true
+}
+
+// a rolled back import is still a full report, just with 422
+func writeImportReport(w http.ResponseWriter, report models.ImportReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Committed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// decodeTeachers/decodeStudents - the CSV plus the per-row checks, shared by the sync handlers and the import jobs
+func decodeTeachers(body io.Reader) ([]models.Teacher, []error, error) {
+	teachers, rowErrs, err := decodeCSV[models.Teacher](body)
+	for i, t := range teachers {
+		if rowErrs[i] == nil {
+			rowErrs[i] = validatePerson(t.FirstName, t.LastName, t.Email)
+		}
+	}
+	return teachers, rowErrs, err
+}
+
+func decodeStudents(body io.Reader) ([]models.Student, []error, error) {
+	students, rowErrs, err := decodeCSV[models.Student](body)
+	for i, s := range students {
+		if rowErrs[i] == nil {
+			rowErrs[i] = validatePerson(s.FirstName, s.LastName, s.Email)
+		}
+	}
+	return students, rowErrs, err
+}
+
+// enqueueImport checks the file up front (a broken CSV is still a 400) and hands it to a job
+func enqueueImport[T any](w http.ResponseWriter, r *http.Request, jobType, mode string, decode func(io.Reader) ([]T, []error, error)) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "CSV file too large or unreadable ⚠️", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if _, _, err := decode(bytes.NewReader(data)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := jobs.Enqueue(jobType, importPayload{CSV: string(data), Mode: mode})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAccepted(w, job)
+}
+
+//! 1️⃣☑️ IMPORT teachers from CSV /teachers/import?mode=atomic|best-effort
+// header: first_name,last_name,email,class,subject - existing teachers are matched (and updated) by email
+// Prefer: respond-async (or ?async=true) runs it as a background job -> 202 + Location: /jobs/{id}
+func ImportTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	mode, ok := readImport(w, r)
+	if !ok {
+		return
+	}
+	if wantsAsync(r) {
+		enqueueImport(w, r, jobTeachersImport, mode, decodeTeachers)
+		return
+	}
+
+	teachers, rowErrs, err := decodeTeachers(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := sqlconnect.ImportTeachersDbHandler(r.Context(), teachers, rowErrs, mode, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeImportReport(w, report)
+}
+
+//! 2️⃣☑️ IMPORT students from CSV /students/import?mode=atomic|best-effort
+// header: first_name,last_name,email,class - existing students are matched (and updated) by email
+// Prefer: respond-async (or ?async=true) runs it as a background job -> 202 + Location: /jobs/{id}
+func ImportStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	mode, ok := readImport(w, r)
+	if !ok {
+		return
+	}
+	if wantsAsync(r) {
+		enqueueImport(w, r, jobStudentsImport, mode, decodeStudents)
+		return
+	}
+
+	students, rowErrs, err := decodeStudents(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := sqlconnect.ImportStudentsDbHandler(r.Context(), students, rowErrs, mode, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeImportReport(w, report)
+}
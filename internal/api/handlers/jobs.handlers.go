@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// Job types
+const (
+	jobTeachersImport = "teachers.import"
+	jobStudentsImport = "students.import"
+	jobTeachersExport = "teachers.export"
+	jobStudentsExport = "students.export"
+	jobClassesPromote = "classes.promote"
+)
+
+type importPayload struct {
+	CSV  string `json:"csv"`
+	Mode string `json:"mode"`
+}
+
+type exportPayload struct {
+	Query  string `json:"query"` // query-string of the original /export request
+	Format string `json:"format"`
+}
+
+type promotePayload struct {
+	ClassID int                     `json:"class_id"`
+	Request models.PromotionRequest `json:"request"`
+}
+
+// exportResult is what GET /jobs/{id} shows for a finished export, the file itself is at /jobs/{id}/result
+type exportResult struct {
+	Filename    string `json:"filename"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Rows        int    `json:"rows"`
+}
+
+// RegisterJobs tells the runner how to execute each job type - call it before jobs.Start()
+func RegisterJobs() {
+	jobs.Register(jobTeachersImport, importJob(decodeTeachers, sqlconnect.ImportTeachersDbHandler))
+	jobs.Register(jobStudentsImport, importJob(decodeStudents, sqlconnect.ImportStudentsDbHandler))
+	jobs.Register(jobTeachersExport, exportJob("teachers", sqlconnect.ExportTeachersDbHandler))
+	jobs.Register(jobStudentsExport, exportJob("students", sqlconnect.ExportStudentsDbHandler))
+	jobs.Register(jobClassesPromote, promoteJob)
+}
+
+// wantsAsync - the client opted into a background job (RFC 7240 Prefer: respond-async, or ?async=true)
+func wantsAsync(r *http.Request) bool {
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		return true
+	}
+	for _, prefer := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(prefer, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func withLinks(job models.Job) models.Job {
+	self := fmt.Sprintf("/jobs/%d", job.ID)
+	job.Links = map[string]string{"self": self}
+	if !job.Finished() {
+		job.Links["cancel"] = self + "/cancel"
+	}
+	if job.Status == models.JobSucceeded {
+		job.Links["result"] = self + "/result"
+	}
+	return job
+}
+
+// writeAccepted - 202 with the job to poll
+func writeAccepted(w http.ResponseWriter, job models.Job) {
+	job = withLinks(job)
+	w.Header().Set("Location", job.Links["self"])
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func importJob[T any](decode func(io.Reader) ([]T, []error, error),
+	run func(context.Context, []T, []error, string, func(int, int)) (models.ImportReport, error)) jobs.Handler {
+	return func(ctx context.Context, job models.Job, report func(done, total int)) (any, error) {
+		var p importPayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return nil, jobs.Permanent(err)
+		}
+		rows, rowErrs, err := decode(strings.NewReader(p.CSV))
+		if err != nil {
+			return nil, jobs.Permanent(err)
+		}
+		return run(ctx, rows, rowErrs, p.Mode, report)
+	}
+}
+
+// exportDir - EXPORT_DIR, or a folder in the OS temp-dir
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "go-rest-api-exports")
+}
+
+func exportPath(jobId int, format string) string {
+	return filepath.Join(exportDir(), fmt.Sprintf("job-%d.%s", jobId, format))
+}
+
+func exportJob[T any](name string, export func(*http.Request, func(T) error) error) jobs.Handler {
+	return func(ctx context.Context, job models.Job, report func(done, total int)) (any, error) {
+		var p exportPayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return nil, jobs.Permanent(err)
+		}
+		contentType, ok := exportContentTypes[p.Format]
+		if !ok {
+			return nil, jobs.Permanent(fmt.Errorf("unknown export format %q", p.Format))
+		}
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/"+name+"/export?"+p.Query, nil)
+		if err != nil {
+			return nil, jobs.Permanent(err)
+		}
+
+		if err := os.MkdirAll(exportDir(), 0o750); err != nil {
+			return nil, err
+		}
+		path := exportPath(job.ID, p.Format)
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		var zero T
+		out, err := newExportWriter(p.Format, file, name, zero)
+		if err == nil {
+			rows := 0
+			err = export(r, func(row T) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				rows++
+				if rows%exportFlushEvery == 0 {
+					report(rows, 0)
+				}
+				return out.WriteRow(row)
+			})
+			report(rows, 0)
+			if err == nil {
+				err = out.Close()
+			}
+			if err == nil {
+				return exportResult{
+					Filename:    fmt.Sprintf("%s-%s.%s", name, strings.Split(job.CreatedAt, " ")[0], p.Format),
+					Format:      p.Format,
+					ContentType: contentType,
+					Rows:        rows,
+				}, nil
+			}
+		}
+		os.Remove(path) // no half-written files
+		return nil, err
+	}
+}
+
+func promoteJob(ctx context.Context, job models.Job, report func(done, total int)) (any, error) {
+	var p promotePayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	// one transaction, so there's nothing in between to report
+	result, err := sqlconnect.PromoteClassDbHandler(p.ClassID, p.Request)
+	if err != nil {
+		return nil, err
+	}
+	report(len(result.StudentIDs), len(result.StudentIDs))
+	return result, nil
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH jobs ?status=queued|running|succeeded|failed|cancelled&type=
+func GetJobsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := sqlconnect.GetJobsDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range list {
+		list[i] = withLinks(list[i])
+	}
+
+	resp := struct {
+		Status string       `json:"status"`
+		Count  int          `json:"count"`
+		Data   []models.Job `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(list),
+		Data:   list,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single job /jobs/id - status, progress, error and links
+func GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	job, err := sqlconnect.GetJobDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !job.Finished() {
+		w.Header().Set("Retry-After", "2")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withLinks(job))
+}
+
+//! 3️⃣☑️ CANCEL a job /jobs/id/cancel
+func CancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	job, err := jobs.Cancel(id)
+	if err != nil {
+		status := http.StatusConflict
+		if job.ID == 0 {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withLinks(job))
+}
+
+//! 4️⃣☑️ GET the result of a finished job /jobs/id/result - the file of an export, the JSON result otherwise
+func GetJobResultHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	job, err := sqlconnect.GetJobDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if job.Status != models.JobSucceeded {
+		http.Error(w, fmt.Sprintf("job %d is %s, there's no result ⚠️", id, job.Status), http.StatusConflict)
+		return
+	}
+
+	if job.Type != jobTeachersExport && job.Type != jobStudentsExport {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(job.Result)
+		return
+	}
+
+	var result exportResult
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		http.Error(w, "Invalid export result ⚠️", http.StatusInternalServerError)
+		return
+	}
+	file, err := os.Open(exportPath(job.ID, result.Format))
+	if err != nil {
+		http.Error(w, "Export file no longer available ⚠️", http.StatusGone)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, result.Filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, file)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/internal/ws"
+)
+
+// Live attendance: everyone taking attendance for a class shares a room and sees each other's marks.
+// Rooms live in this process - with several replicas, only clients on the same one see each other live.
+
+var attendanceRooms = ws.NewHub(32)
+
+// liveMessage - the JSON text frames of /classes/{id}/attendance/live
+//
+//	client -> server: {"type": "mark", "date": "2025-09-01", "period": 1, "records": [{"student_id": 7, "status": "late"}]}
+//	server -> client: {"type": "presence", "users": [...]}, {"type": "attendance.marked", ...}, {"type": "error", "message": "..."}
+type liveMessage struct {
+	Type     string              `json:"type"`
+	ClassID  int                 `json:"class_id,omitempty"`
+	Date     string              `json:"date,omitempty"`
+	Period   int                 `json:"period,omitempty"`
+	Records  []models.Attendance `json:"records,omitempty"`
+	MarkedBy int                 `json:"marked_by,omitempty"` // execs mark for a teacher
+	By       *auth.Principal     `json:"by,omitempty"`
+	Users    []any               `json:"users,omitempty"`
+	Message  string              `json:"message,omitempty"`
+}
+
+func encodeLive(msg liveMessage) []byte {
+	data, _ := json.Marshal(msg)
+	return data
+}
+
+func broadcastPresence(classId int) {
+	room := strconv.Itoa(classId)
+	attendanceRooms.Broadcast(room, encodeLive(liveMessage{Type: "presence", ClassID: classId, Users: attendanceRooms.Members(room)}))
+}
+
+// broadcastMarked sends the whole sheet of date/period - clients just replace what they have
+func broadcastMarked(classId int, date string, period int, sheet []models.Attendance, by *auth.Principal) {
+	attendanceRooms.Broadcast(strconv.Itoa(classId), encodeLive(liveMessage{Type: "attendance.marked", ClassID: classId,
+		Date: date, Period: period, Records: sheet, By: by}))
+}
+
+//! 1️⃣☑️ LIVE attendance of a class (WebSocket) /classes/id/attendance/live
+// behind the auth mw - execs may join any class, teachers only the classes they teach
+func LiveAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required ⚠️", http.StatusUnauthorized)
+		return
+	}
+
+	class, err := sqlconnect.GetClassDbHandler(classId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if principal.Role == auth.RoleTeacher {
+		if err := sqlconnect.TeachesClassDbHandler(principal.ID, class.Name); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		return // the response is written already
+	}
+	client := attendanceRooms.Join(strconv.Itoa(classId), conn, principal)
+	broadcastPresence(classId)
+	defer func() {
+		attendanceRooms.Leave(client)
+		broadcastPresence(classId)
+	}()
+
+	for {
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			return // closed, timed out or kicked for being too slow
+		}
+		var msg liveMessage
+		if opcode != ws.OpText || json.Unmarshal(data, &msg) != nil {
+			client.Send(encodeLive(liveMessage{Type: "error", Message: "expected a JSON text message ⚠️"}))
+			continue
+		}
+
+		switch msg.Type {
+		case "mark":
+			sheet := models.AttendanceSheet{Date: msg.Date, Period: msg.Period, Records: msg.Records, MarkedBy: msg.MarkedBy}
+			if sheet.Period == 0 {
+				sheet.Period = 1
+			}
+			if principal.Role == auth.RoleTeacher {
+				sheet.MarkedBy = principal.ID
+			}
+			if err := validateAttendanceSheet(sheet); err != nil {
+				client.Send(encodeLive(liveMessage{Type: "error", Message: err.Error()}))
+				continue
+			}
+			marked, err := sqlconnect.MarkClassAttendanceDbHandler(classId, sheet)
+			if err != nil {
+				client.Send(encodeLive(liveMessage{Type: "error", Message: err.Error()}))
+				continue
+			}
+			broadcastMarked(classId, sheet.Date, sheet.Period, marked, &principal)
+		default:
+			client.Send(encodeLive(liveMessage{Type: "error", Message: "unknown message type " + strconv.Quote(msg.Type) + " ⚠️"}))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// Reports - numbers instead of rows, everything is computed in SQL
+
+//! 1️⃣☑️ GET /teachers/count
+func CountTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := sqlconnect.CountTeachersDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+		Count  int    `json:"count"`
+	}{
+		Status: "success",
+		Count:  count,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET /teachers/stats?group_by=subject
+func GetTeacherStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeStats(w, r, sqlconnect.GetTeacherStatsDbHandler)
+}
+
+//! 3️⃣☑️ GET /students/stats?group_by=class
+func GetStudentStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeStats(w, r, sqlconnect.GetStudentStatsDbHandler)
+}
+
+func writeStats(w http.ResponseWriter, r *http.Request, dbOps func(*http.Request) ([]models.GroupCount, error)) {
+	stats, err := dbOps(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status  string              `json:"status"`
+		GroupBy string              `json:"group_by"`
+		Count   int                 `json:"count"`
+		Data    []models.GroupCount `json:"data"`
+	}{
+		Status:  "success",
+		GroupBy: r.URL.Query().Get("group_by"),
+		Count:   len(stats),
+		Data:    stats,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,31 +1,273 @@
 package handlers
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
 )
 
-func StudentsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		w.Write([]byte("Hello GET method on Students-Route ✅"))
-		fmt.Println("Hello GET method on Students-Route ✅")
+// CRUD ⭐ - mirrors the teachers handlers
+//! 1️⃣☑️ GET/FETCH student(s)
+func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	var students []models.Student
+	students, err := sqlconnect.GetStudentsDbHandler(students, r) // db ops.
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Student `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(students),
+		Data:   students,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single-student /id
+func GetStudentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	student, err := sqlconnect.GetStudentDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(student)
+}
+
+//! 3️⃣☑️ ADD/POST Student(s)
+func AddStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	var newStudents []models.Student
+	err := utils.DecodeBody(r, &newStudents)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	// every class has to exist in the classes table
+	classes := make([]string, len(newStudents))
+	for i, s := range newStudents {
+		classes[i] = s.Class
+	}
+	if err := sqlconnect.ValidateClasses(classes...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	addedStudents, err := sqlconnect.AddStudentsDbHandler(newStudents)
+	if errors.As(err, new(*sqlconnect.ValidationError)) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Student `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedStudents),
+		Data:   addedStudents,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 4️⃣☑️ UPDATE/PUT Students/id
+func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updatedStudent models.Student
+	err = utils.DecodeBody(r, &updatedStudent)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	if err := sqlconnect.ValidateClasses(updatedStudent.Class); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedStudentFromDb, err := sqlconnect.UpdateStudentDbHandler(id, updatedStudent)
+	if err != nil {
+		log.Println(err)
+		if errors.As(err, new(*sqlconnect.ValidationError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedStudentFromDb)
+}
+
+//! 5️⃣☑️ Partially-Edit/PATCH Single Student/id
+func PatchStudentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePatchedClasses(updates); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedStudent, err := sqlconnect.PatchSingleStudentDbOps(id, updates)
+	if err != nil {
+		if errors.As(err, new(*sqlconnect.ValidationError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedStudent)
+}
+
+//! 6️⃣☑️ PATCH Multiple-Students
+func PatchStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	var updates []map[string]any
+	err := utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "ERROR: Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePatchedClasses(updates...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.PatchStudentsDbHandler(updates)
+	if err != nil {
+		if errors.As(err, new(*sqlconnect.ValidationError)) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//! 7️⃣☑️ DELETE Single Student/id
+func DeleteStudentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteSingleStudentDbHandler(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Student successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 8️⃣☑️ DELETE Multiple-Students
+func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	err := utils.DecodeBody(r, &ids)
+	if err != nil {
+		http.Error(w, "ERROR: Invalid request-payload ⚠️", http.StatusBadRequest)
 		return
-	case http.MethodPost:
-		w.Write([]byte("Hello Post method on Students-Route ✅"))
-		fmt.Println("Hello Post method on Students-Route ✅")
+	}
+
+	deletedIds, err := sqlconnect.DeleteStudentsDbHandler(ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
-	case http.MethodPatch:
-		w.Write([]byte("Hello Patch method on Students-Route ✅"))
-		fmt.Println("Hello Patch method on Students-Route ✅")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Status     string `json:"status"`
+		DeletedIDs []int  `json:"deleted_ids"`
+	}{
+		Status:     "Students Successfully Deleted ✅",
+		DeletedIDs: deletedIds,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 9️⃣☑️ GET teachers of a student /id/teachers
+func GetStudentTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid student-ID ⚠️", http.StatusBadRequest)
 		return
-	case http.MethodDelete:
-		w.Write([]byte("Hello Delete method on Students-Route ✅"))
-		fmt.Println("Hello Delete method on Students-Route ✅")
+	}
+
+	teachers, err := sqlconnect.GetStudentTeachersDbHandler(id, r)
+	if errors.Is(err, sqlconnect.ErrStudentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
-	default:
-		w.Write([]byte("Hello UNKNOWN method on Students-Route!"))
-		fmt.Println("Hello UNKNOWN method on Students-Route !")
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-}
\ No newline at end of file
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Teacher `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(teachers),
+		Data:   teachers,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+func validateSubject(s models.Subject) error {
+	if s.Name == "" || s.Code == "" {
+		return fmt.Errorf("subject name and code are required ⚠️")
+	}
+	return nil
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH subjects
+func GetSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	subjects, err := sqlconnect.GetSubjectsDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(subjects),
+		Data:   subjects,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single-subject /id
+func GetSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	subject, err := sqlconnect.GetSubjectDbHandler(id)
+	if errors.Is(err, sqlconnect.ErrSubjectNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subject)
+}
+
+//! 3️⃣☑️ ADD/POST Subject(s)
+func AddSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	var newSubjects []models.Subject
+	err := utils.DecodeBody(r, &newSubjects)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, s := range newSubjects {
+		if err := validateSubject(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	addedSubjects, err := sqlconnect.AddSubjectsDbHandler(newSubjects)
+	if errors.Is(err, sqlconnect.ErrSubjectTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSubjects),
+		Data:   addedSubjects,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 4️⃣☑️ UPDATE/PUT Subjects/id
+func UpdateSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid subject-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updatedSubject models.Subject
+	err = utils.DecodeBody(r, &updatedSubject)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validateSubject(updatedSubject); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedSubjectFromDb, err := sqlconnect.UpdateSubjectDbHandler(id, updatedSubject)
+	switch {
+	case errors.Is(err, sqlconnect.ErrSubjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrSubjectTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSubjectFromDb)
+}
+
+//! 5️⃣☑️ Partially-Edit/PATCH Subject/id
+func PatchSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid subject-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	updatedSubject, err := sqlconnect.PatchSubjectDbHandler(id, updates, validateSubject)
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrSubjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrSubjectTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSubject)
+}
+
+//! 6️⃣☑️ DELETE Subject/id
+func DeleteSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid subject-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteSubjectDbHandler(id)
+	switch {
+	case errors.Is(err, sqlconnect.ErrSubjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrSubjectInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Subject successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
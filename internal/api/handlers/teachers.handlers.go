@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/iamskyy111/go-rest-api/internal/models"
 	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
 )
 
 // 💡 All Ops. apart from GET requires db.Exec()
@@ -48,7 +50,13 @@ func GetTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	teacher, err := sqlconnect.GetTeacherDbHandler(id)
+	includes, err := sqlconnect.ParseIncludes(r, sqlconnect.TeacherIncludes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	teacher, err := sqlconnect.GetTeacherDbHandler(id, includes...)
 	if err!=nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -63,12 +71,22 @@ func GetTeacherHandler(w http.ResponseWriter, r *http.Request) {
 func AddTeachersHandler(w http.ResponseWriter, r *http.Request){
 
 	var newTeachers []models.Teacher
-	err:=json.NewDecoder(r.Body).Decode(&newTeachers) // we can add 1 or multiple values in a list
+	err:=utils.DecodeBody(r, &newTeachers) // we can add 1 or multiple values in a list
 	if err != nil {
 		http.Error(w,"Invalid Request Body!",http.StatusBadRequest)
 		return
 	}
 
+	// every class has to exist in the classes table
+	classes := make([]string, len(newTeachers))
+	for i, t := range newTeachers {
+		classes[i] = t.Class
+	}
+	if err := sqlconnect.ValidateClasses(classes...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Connect to DB
 	addedTeachers, err := sqlconnect.AddTeachersDbHandler(newTeachers)
 	if err!=nil {
@@ -103,12 +121,17 @@ func UpdateTeacherHandler(w http.ResponseWriter, r *http.Request){
 
 	// decode JSON body into the model
 	var updatedTeacher models.Teacher
-	err=json.NewDecoder(r.Body).Decode(&updatedTeacher)
+	err=utils.DecodeBody(r, &updatedTeacher)
 	if err != nil {
 		http.Error(w,"Invalid request-payload ⚠️",http.StatusBadRequest)
 		return
 	}
 
+	if err := sqlconnect.ValidateClasses(updatedTeacher.Class); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// connect to the DB
 	updatedTeacherFromDb, err := sqlconnect.UpdateTeachersDbHandler(id,updatedTeacher)
 	if err!=nil {
@@ -134,13 +157,18 @@ func PatchTeacherHandler(w http.ResponseWriter, r *http.Request){
 
 	// decode JSON body into the model
 	var updates map[string]any
-	err=json.NewDecoder(r.Body).Decode(&updates)
+	err=utils.DecodeBody(r, &updates)
 	if err != nil {
 		log.Println("ERROR:",err)
 		http.Error(w,"Invalid request-payload ⚠️",http.StatusBadRequest)
 		return
 	}
 
+	if err := validatePatchedClasses(updates); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// connect to the DB
 	updatedteacher, err := sqlconnect.PatchSingleTeacherDbOps(id, updates)
 	if err!=nil {
@@ -155,12 +183,17 @@ func PatchTeacherHandler(w http.ResponseWriter, r *http.Request){
 //! 5️⃣☑️ PATCH Multiple-Teachers
 func PatchTeachersHandler(w http.ResponseWriter, r *http.Request){
 	var updates []map[string]any
-	err:=json.NewDecoder(r.Body).Decode(&updates)
+	err:=utils.DecodeBody(r, &updates)
 	if err != nil {
 		http.Error(w,"ERROR: Invalid request-payload ⚠️",http.StatusBadRequest)
 		return
 	}
 
+	if err := validatePatchedClasses(updates...); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// connect to the DB
 	err = sqlconnect.PatchTeachersDbHandler(updates)
 	if err!=nil {
@@ -209,7 +242,7 @@ func DeleteTeacherHandler(w http.ResponseWriter, r *http.Request){
  //! 7️⃣☑️ DELETE Multiple-Teachers
  func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request){
 	var ids []int
-	err:=json.NewDecoder(r.Body).Decode(&ids)
+	err:=utils.DecodeBody(r, &ids)
 	if err != nil {
 		http.Error(w,"ERROR: Invalid request-payload ⚠️",http.StatusBadRequest)
 		return
@@ -234,3 +267,34 @@ func DeleteTeacherHandler(w http.ResponseWriter, r *http.Request){
 	json.NewEncoder(w).Encode(resp)
 }
 
+
+
+//! 8️⃣☑️ GET students of a teacher /id/students
+func GetTeacherStudentsHandler(w http.ResponseWriter, r *http.Request){
+	id,err:= strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w,"Invalid teacher-ID ⚠️",http.StatusBadRequest)
+		return
+	}
+
+	students, err := sqlconnect.GetTeacherStudentsDbHandler(id, r)
+	if errors.Is(err, sqlconnect.ErrTeacherNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err!=nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []models.Student  `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(students),
+		Data:   students,
+	}
+	w.Header().Set("Content-Type","application/json")
+	json.NewEncoder(w).Encode(resp)
+}
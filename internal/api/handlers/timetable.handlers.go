@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// writeSlotError answers clashes with a 409 that lists the conflicting slot(s)
+func writeSlotError(w http.ResponseWriter, err error) {
+	var clash *sqlconnect.ClashError
+	var invalid *sqlconnect.ValidationError
+	switch {
+	case errors.Is(err, sqlconnect.ErrSlotNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, sqlconnect.ErrSlotTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case !errors.As(err, &clash):
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	resp := struct {
+		Status  string                  `json:"status"`
+		Message string                  `json:"message"`
+		Clashes []models.TimetableClash `json:"clashes"`
+	}{
+		Status:  "conflict",
+		Message: clash.Error(),
+		Clashes: clash.Clashes,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeSlots(w http.ResponseWriter, slots []models.TimetableSlot) {
+	resp := struct {
+		Status string                 `json:"status"`
+		Count  int                    `json:"count"`
+		Data   []models.TimetableSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(slots),
+		Data:   slots,
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH timetable
+func GetTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	slots, err := sqlconnect.GetTimetableDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeSlots(w, slots)
+}
+
+//! 2️⃣☑️ GET/FETCH single-slot /id
+func GetTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	slot, err := sqlconnect.GetTimetableSlotDbHandler(id)
+	if err != nil {
+		writeSlotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slot)
+}
+
+//! 3️⃣☑️ ADD/POST Slot(s)
+func AddTimetableSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	var newSlots []models.TimetableSlot
+	err := utils.DecodeBody(r, &newSlots)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	for _, slot := range newSlots {
+		if err := sqlconnect.ValidateTimetableSlot(slot); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	added, err := sqlconnect.AddTimetableSlotsDbHandler(newSlots)
+	if err != nil {
+		writeSlotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeSlots(w, added)
+}
+
+//! 4️⃣☑️ UPDATE/PUT Slot/id
+func UpdateTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid slot-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var slot models.TimetableSlot
+	err = utils.DecodeBody(r, &slot)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := sqlconnect.ValidateTimetableSlot(slot); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.UpdateTimetableSlotDbHandler(id, slot)
+	if err != nil {
+		writeSlotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+//! 5️⃣☑️ Partially-Edit/PATCH Slot/id
+func PatchTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid slot-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]any
+	err = utils.DecodeBody(r, &updates)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.PatchTimetableSlotDbHandler(id, updates)
+	if err != nil {
+		writeSlotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+//! 6️⃣☑️ DELETE Slot/id
+func DeleteTimetableSlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid slot-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteTimetableSlotDbHandler(id)
+	if err != nil {
+		writeSlotError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Timetable slot successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 7️⃣☑️ GET teacher's week /teachers/id/timetable (?format=ics&week=YYYY-MM-DD for iCalendar)
+func GetTeacherTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	teacherId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid teacher-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := sqlconnect.GetTeacherTimetableDbHandler(teacherId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "ics" {
+		week := time.Now()
+		if val := r.URL.Query().Get("week"); val != "" {
+			week, err = time.Parse(time.DateOnly, val)
+			if err != nil {
+				http.Error(w, "Invalid week, expected YYYY-MM-DD ⚠️", http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="timetable-teacher-%d.ics"`, teacherId))
+		w.Write([]byte(timetableICS(slots, week)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeSlots(w, slots)
+}
+
+//! 8️⃣☑️ GET class' week /classes/id/timetable
+func GetClassTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	classId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid class-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := sqlconnect.GetClassTimetableDbHandler(classId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeSlots(w, slots)
+}
+
+// timetableICS renders the slots as weekly recurring events, starting in the week of `week`.
+// Times are "floating" (no TZID) - they mean school-local time wherever the calendar is opened.
+func timetableICS(slots []models.TimetableSlot, week time.Time) string {
+	monday := week.AddDate(0, 0, -((int(week.Weekday()) + 6) % 7))
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	line := func(s string) { b.WriteString(s + "\r\n") } // RFC 5545 wants CRLF
+	escape := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//go-rest-api//timetable//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, s := range slots {
+		date := monday.AddDate(0, 0, s.Day-1).Format("20060102")
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:timetable-slot-%d@go-rest-api", s.ID))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART:" + date + "T" + icsTime(s.StartTime))
+		line("DTEND:" + date + "T" + icsTime(s.EndTime))
+		line("RRULE:FREQ=WEEKLY")
+		line("SUMMARY:" + escape(s.Subject+" - "+s.Class))
+		line("LOCATION:" + escape(s.Room))
+		line(fmt.Sprintf("DESCRIPTION:Period %d", s.Period))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// "08:50:00" -> "085000"
+func icsTime(t string) string {
+	t = strings.ReplaceAll(t, ":", "")
+	for len(t) < 6 {
+		t += "0"
+	}
+	return t[:6]
+}
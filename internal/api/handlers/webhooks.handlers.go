@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+	"github.com/iamskyy111/go-rest-api/internal/webhooks"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const minSecretLength = 16
+
+// validateEventFilter - "*", an event type, or "teacher.*"/"student.*"
+func validateEventFilter(filter []string) error {
+	for _, pattern := range filter {
+		if pattern == "*" || slices.Contains(events.Types, pattern) {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(pattern, ".*"); ok && (prefix == "teacher" || prefix == "student") {
+			continue
+		}
+		return fmt.Errorf("unknown event %q, expected one of %s, teacher.*, student.* or * ⚠️", pattern, strings.Join(events.Types, ", "))
+	}
+	return nil
+}
+
+func validateWebhook(wh models.Webhook) error {
+	if err := webhooks.ValidateURL(wh.URL); err != nil {
+		return err
+	}
+	if len(wh.Events) == 0 {
+		return fmt.Errorf("events is required, use [\"*\"] for everything ⚠️")
+	}
+	if wh.Secret != "" && len(wh.Secret) < minSecretLength {
+		return fmt.Errorf("secret must be at least %d characters ⚠️", minSecretLength)
+	}
+	return validateEventFilter(wh.Events)
+}
+
+// validatePatchedWebhook checks only the fields present in the PATCH body
+func validatePatchedWebhook(patch models.Webhook) error {
+	if patch.URL != "" {
+		if err := webhooks.ValidateURL(patch.URL); err != nil {
+			return err
+		}
+	}
+	if patch.Secret != "" && len(patch.Secret) < minSecretLength {
+		return fmt.Errorf("secret must be at least %d characters ⚠️", minSecretLength)
+	}
+	return validateEventFilter(patch.Events)
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// the secret is only ever shown in the response to POST /webhooks
+func redactWebhook(wh models.Webhook) models.Webhook {
+	wh.Secret = ""
+	return wh
+}
+
+// CRUD ⭐
+//! 1️⃣☑️ GET/FETCH webhooks
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := sqlconnect.GetWebhooksDbHandler(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range hooks {
+		hooks[i] = redactWebhook(hooks[i])
+	}
+
+	resp := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Webhook `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(hooks),
+		Data:   hooks,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 2️⃣☑️ GET/FETCH single-webhook /id
+func GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := sqlconnect.GetWebhookDbHandler(id)
+	if errors.Is(err, sqlconnect.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redactWebhook(hook))
+}
+
+//! 3️⃣☑️ ADD/POST webhook
+// body: {"url": "https://lms.example.com/hooks", "events": ["teacher.*", "student.created"], "secret": "..."}
+// without a secret one is generated - either way it's only returned this once
+func AddWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var newWebhook models.Webhook
+	err := utils.DecodeBody(r, &newWebhook)
+	if err != nil {
+		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+	if err := validateWebhook(newWebhook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if newWebhook.Secret == "" {
+		newWebhook.Secret = newWebhookSecret()
+	}
+
+	added, err := sqlconnect.AddWebhookDbHandler(newWebhook)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/webhooks/%d", added.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+//! 4️⃣☑️ Partially-Edit/PATCH webhook/id - url, events, secret, description, active
+func PatchWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	var patch models.Webhook
+	err = utils.DecodeBody(r, &patch)
+	if err != nil {
+		http.Error(w, "Invalid request-payload ⚠️", http.StatusBadRequest)
+		return
+	}
+	if err := validatePatchedWebhook(patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := sqlconnect.PatchWebhookDbHandler(id, patch)
+	if errors.Is(err, sqlconnect.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redactWebhook(updated))
+}
+
+//! 5️⃣☑️ DELETE webhook/id
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlconnect.DeleteWebhookDbHandler(id)
+	if errors.Is(err, sqlconnect.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}{
+		Status: "Webhook successfully DELETED ✅",
+		ID:     id,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+//! 6️⃣☑️ GET the delivery log /webhooks/id/deliveries?status=failed&event=teacher.created
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := sqlconnect.GetWebhookDeliveriesDbHandler(id, r)
+	if errors.Is(err, sqlconnect.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []models.WebhookDelivery `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(deliveries),
+		Data:   deliveries,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+//! 7️⃣☑️ REDELIVER a logged delivery /webhooks/id/deliveries/deliveryId/redeliver
+func RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+	deliveryId, err := strconv.Atoi(r.PathValue("deliveryId"))
+	if err != nil {
+		http.Error(w, "Invalid delivery-ID ⚠️", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := sqlconnect.GetWebhookDeliveryDbHandler(id, deliveryId)
+	if errors.Is(err, sqlconnect.ErrDeliveryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	job, err := webhooks.Redeliver(delivery)
+	if errors.Is(err, sqlconnect.ErrDeliveryUnfinished) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAccepted(w, job)
+}
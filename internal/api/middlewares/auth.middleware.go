@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
+	"github.com/iamskyy111/go-rest-api/internal/ws"
+)
+
+// AuthMiddleware lets through requests with a valid exec/teacher token (Authorization: Bearer <token>)
+// and puts the principal on the request context - see auth.FromContext.
+// Browsers can't set headers on a WebSocket handshake, so upgrade requests may pass ?access_token= instead.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && ws.IsUpgrade(r) {
+			token = r.URL.Query().Get("access_token")
+		}
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "Authentication required ⚠️", http.StatusUnauthorized)
+			return
+		}
+
+		principal, err := auth.Verify(token)
+		if errors.Is(err, auth.ErrNoSecret) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
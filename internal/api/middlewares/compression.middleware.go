@@ -1,40 +1,268 @@
 package middlewares
 
 import (
+	"bytes"
 	"compress/gzip"
-	"fmt"
+	"compress/zlib"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/iamskyy111/go-rest-api/internal/ws"
 )
 
 // Makes difference on bigger payloads - Negligible on small data/payload (Static pages, small images etc.)
 // CPU Overhead - Use only when needed
+// So: bodies under MinSize and content that's compressed already (XLSX, images) go out as they are.
+
+type CompressionOptions struct {
+	MinSize      int      // bytes; smaller bodies aren't worth the CPU
+	ContentTypes []string // media types worth compressing, "text/*" covers the whole group
+	Level        int      // 1 (fast) - 9 (small), 0 = each encoder's default
+}
+
+var DefaultCompressionOptions = CompressionOptions{
+	MinSize: 1024,
+	ContentTypes: []string{"text/*", "application/json", "application/xml", "application/javascript",
+		"application/x-ndjson", "application/msgpack", "image/svg+xml"},
+}
+
+// encodings we can produce, preferred first (on equal q-values)
+var compressionEncodings = []string{"br", "gzip", "deflate"}
+
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return CompressionMiddlewareWithOptions(DefaultCompressionOptions)(next)
+}
+
+func CompressionMiddlewareWithOptions(options CompressionOptions) func(http.Handler) http.Handler {
+	pools := map[string]*sync.Pool{}
+	for _, encoding := range compressionEncodings {
+		pools[encoding] = &sync.Pool{New: func() any { return newEncoder(encoding, options.Level) }}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// a websocket takes the connection over - there's no response body to compress
+			if ws.IsUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// the response depends on Accept-Encoding, whichever way we decide - caches need to know
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r) // move to thex next mw()
+				return
+			}
 
-func CompressionMiddleware(next http.Handler) http.Handler{
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if the client accepts 'gzip' encoding
-		if !strings.Contains(r.Header.Get("Accept-Encoding"),"gzip"){
-			next.ServeHTTP(w,r) // move to thex next mw()
+			cw := &compressResponseWriter{ResponseWriter: w, options: options, encoding: encoding, pool: pools[encoding]}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the best of compressionEncodings the Accept-Encoding header allows ("" = identity).
+// "br;q=0.8, gzip" -> gzip; "*" covers everything not listed; no header at all means no compression.
+func negotiateEncoding(acceptEncoding []string) string {
+	q := map[string]float64{}
+	for _, header := range acceptEncoding {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+			quality := 1.0
+			if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					quality = parsed
+				}
+			}
+			q[coding] = quality
 		}
+	}
 
-		// Set the resp. header
-		w.Header().Set("Content-Encoding","gzip")
-		gz:=gzip.NewWriter(w)
-		defer gz.Close()
+	best, bestQ := "", 0.0
+	for _, encoding := range compressionEncodings {
+		quality, listed := q[encoding]
+		if !listed {
+			quality, listed = q["*"]
+		}
+		if listed && quality > bestQ {
+			best, bestQ = encoding, quality
+		}
+	}
+	return best
+}
 
-		// Wrap the ResponseWriter
-		w = &gzipResponseWriter{ResponseWriter: w, writer: gz}
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
 
-		next.ServeHTTP(w,r)
-		fmt.Println("Sent resp. from Compression Middleware() ☑️")
-	})
+func newEncoder(encoding string, level int) encoder {
+	switch encoding {
+	case "br":
+		if level == 0 {
+			level = 5 // brotli's own default (11) is far too slow for responses
+		}
+		return brotli.NewWriterLevel(nil, level)
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	default: // HTTP's "deflate" is the zlib format, not raw deflate
+		if level == 0 {
+			level = zlib.DefaultCompression
+		}
+		zl, _ := zlib.NewWriterLevel(nil, level)
+		return zl
+	}
 }
-// gzipResponseWriter wraps http.ResponseWriter to write gzipped responses
-type gzipResponseWriter struct{
+
+// compressResponseWriter holds the body back until it knows whether compressing is worth it:
+// MinSize bytes buffered, a Flush (streaming) or the end of the handler decide.
+type compressResponseWriter struct {
 	http.ResponseWriter
-	writer *gzip.Writer
+	options  CompressionOptions
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	enc     encoder // nil: passing through uncompressed
 }
 
-func (g *gzipResponseWriter)Write(b []byte)(int,error){
-	return  g.writer.Write(b)
-}
\ No newline at end of file
+func (cw *compressResponseWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return // superfluous, like net/http's own
+	}
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status) // informational - the real one comes later
+		return
+	}
+	cw.status = status
+	if !bodyAllowed(status) {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		if cw.buf.Len()+len(b) < cw.options.MinSize {
+			return cw.buf.Write(b)
+		}
+		cw.buf.Write(b)
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush pushes what's compressed so far to the client (streamed exports)
+func (cw *compressResponseWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.decide(true) // a stream - its total size doesn't matter
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection (deadlines)
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close decides for what's left in the buffer (a small body) and finishes the compressed stream
+func (cw *compressResponseWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			return nil // nothing was written - net/http sends its default 200
+		}
+		cw.decide(false)
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	cw.pool.Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// decide writes the header and the buffered bytes - compressed if worth and allowed
+func (cw *compressResponseWriter) decide(worthIt bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if worthIt && bodyAllowed(cw.status) && cw.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && cw.compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges") // ranges would be of the compressed bytes
+		// a strong ETag names these exact bytes - the compressed ones are different bytes
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = cw.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressResponseWriter) compressible(contentType string) bool {
+	if contentType == "" {
+		return false // net/http would sniff it from the (compressed) bytes
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		// an event-stream is a trickle of tiny messages that have to arrive one by one - not worth compressing
+		return false
+	}
+	for _, allowed := range cw.options.ContentTypes {
+		if allowed == mediaType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// 204, 304 (and 1xx) never have a body, so there's nothing to encode either
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
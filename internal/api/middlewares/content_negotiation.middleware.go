@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// Handlers keep writing JSON - this mw re-encodes it into whatever the client's Accept header asks for.
+// Anything that isn't JSON (CSV/XLSX exports, iCalendar, plain-text errors) passes through untouched.
+
+var responseMediaTypes = []string{utils.MediaJSON, utils.MediaXML, utils.MediaCSV, utils.MediaMsgpack}
+
+var requestMediaTypes = map[string]bool{
+	utils.MediaJSON:    true,
+	utils.MediaXML:     true,
+	utils.MediaMsgpack: true,
+	utils.MediaCSV:     true, // only the /import endpoints take it
+}
+
+// browsers send CSP violation reports as these - only POST /csp-report reads them
+var reportMediaTypes = []string{"application/csp-report", "application/reports+json"}
+
+func ContentNegotiationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a body in a format we can't read -> 415 before the handler sees it
+		if contentType := r.Header.Get("Content-Type"); contentType != "" && r.ContentLength != 0 &&
+			!requestMediaTypes[utils.NormalizeMediaType(contentType)] && !isReportMediaType(contentType) {
+			http.Error(w, "Unsupported Content-Type - use JSON, XML or MessagePack ⚠️", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		nw := &negotiatingWriter{ResponseWriter: w, accepted: utils.Negotiate(r.Header.Get("Accept"), responseMediaTypes)}
+		next.ServeHTTP(nw, r)
+		nw.finish()
+	})
+}
+
+func isReportMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && slices.Contains(reportMediaTypes, mediaType)
+}
+
+type negotiatingWriter struct {
+	http.ResponseWriter
+	accepted    []string // acceptable media types, best first
+	status      int
+	decided     bool
+	transcoding bool
+	buf         bytes.Buffer
+}
+
+// decide runs on the first WriteHeader/Write: plain JSON the client accepts as-is streams straight through
+func (nw *negotiatingWriter) decide(firstChunk []byte) {
+	nw.decided = true
+	contentType := nw.Header().Get("Content-Type")
+	isJSON := utils.NormalizeMediaType(contentType) == utils.MediaJSON ||
+		(contentType == "" && len(bytes.TrimSpace(firstChunk)) > 0 && strings.ContainsRune("{[", rune(bytes.TrimSpace(firstChunk)[0])))
+	nw.transcoding = isJSON && (len(nw.accepted) == 0 || nw.accepted[0] != utils.MediaJSON)
+}
+
+func (nw *negotiatingWriter) WriteHeader(status int) {
+	if !nw.decided {
+		nw.decide(nil)
+	}
+	if nw.transcoding {
+		nw.status = status
+		return
+	}
+	nw.ResponseWriter.WriteHeader(status)
+}
+
+func (nw *negotiatingWriter) Write(b []byte) (int, error) {
+	if !nw.decided {
+		nw.decide(b)
+	}
+	if nw.transcoding {
+		return nw.buf.Write(b)
+	}
+	return nw.ResponseWriter.Write(b)
+}
+
+func (nw *negotiatingWriter) Flush() {
+	if nw.transcoding {
+		return // the whole document is needed to re-encode it
+	}
+	if f, ok := nw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (nw *negotiatingWriter) Unwrap() http.ResponseWriter {
+	return nw.ResponseWriter
+}
+
+// finish re-encodes the buffered JSON into the first acceptable type that can represent it
+func (nw *negotiatingWriter) finish() {
+	if !nw.transcoding {
+		return
+	}
+	if nw.status == 0 {
+		nw.status = http.StatusOK
+	}
+
+	doc, err := utils.ParseJSON(nw.buf.Bytes())
+	if err != nil {
+		// not really JSON after all - send it on as it came
+		nw.ResponseWriter.WriteHeader(nw.status)
+		nw.ResponseWriter.Write(nw.buf.Bytes())
+		return
+	}
+
+	for _, mediaType := range nw.accepted {
+		var out bytes.Buffer
+		switch mediaType {
+		case utils.MediaXML:
+			err = utils.EncodeXML(&out, doc)
+		case utils.MediaCSV:
+			err = utils.EncodeCSV(&out, doc)
+		case utils.MediaMsgpack:
+			err = utils.EncodeMsgpack(&out, doc)
+		default:
+			continue
+		}
+		if err != nil {
+			continue // e.g. CSV of a single object - try the next type
+		}
+		nw.Header().Del("Content-Length")
+		nw.Header().Set("Content-Type", mediaType+contentTypeCharset(mediaType))
+		nw.ResponseWriter.WriteHeader(nw.status)
+		nw.ResponseWriter.Write(out.Bytes())
+		return
+	}
+
+	nw.Header().Del("Content-Length")
+	http.Error(nw.ResponseWriter, "Not Acceptable - this resource is available as "+strings.Join(representableAs(doc), ", ")+" ⚠️",
+		http.StatusNotAcceptable)
+}
+
+func contentTypeCharset(mediaType string) string {
+	if mediaType == utils.MediaMsgpack {
+		return ""
+	}
+	return "; charset=utf-8"
+}
+
+func representableAs(doc any) []string {
+	types := []string{utils.MediaJSON, utils.MediaXML, utils.MediaMsgpack}
+	if utils.EncodeCSV(&bytes.Buffer{}, doc) == nil {
+		types = append(types, utils.MediaCSV)
+	}
+	return types
+}
@@ -1,51 +1,334 @@
 package middlewares
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 // FOR EXAMPLE:
 // Api is hosted at www.myapi.com
 // fontend server is at www.myfrontend.com
 
-// Allowed origins list:
+// Allowed origins list - the default when neither CORS_CONFIG nor CORS_ALLOWED_ORIGINS is set
 var AllowedOrigins = []string{
 	"https://my-origin.url",
 	"https://www.myfrontend.com",
 	"https://localhost:3000",
 }
 
-func CorsMiddleware(next http.Handler)http.Handler{
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+// CORSOptions - origins are exact ("https://app.example.com"), wildcard subdomains ("https://*.example.com"),
+// regexps (starting with "^") or "*" for everyone (which can't go together with credentials).
+type CORSOptions struct {
+	AllowedOrigins   []string    `json:"allowed_origins"`
+	AllowedMethods   []string    `json:"allowed_methods"`
+	AllowedHeaders   []string    `json:"allowed_headers"` // "*" allows any request header
+	ExposedHeaders   []string    `json:"exposed_headers"`
+	AllowCredentials *bool       `json:"allow_credentials"`
+	MaxAge           int         `json:"max_age"` // seconds browsers may cache a preflight
+	Routes           []CORSRoute `json:"routes"`  // first matching prefix wins
+}
+
+// CORSRoute overrides the options that are set in it for paths starting with PathPrefix
+type CORSRoute struct {
+	PathPrefix string `json:"path_prefix"`
+	CORSOptions
+}
+
+// LoadCORSOptions reads the JSON file at CORS_CONFIG, else the CORS_* env vars:
+// CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS, CORS_EXPOSED_HEADERS (comma separated),
+// CORS_ALLOW_CREDENTIALS (true/false), CORS_MAX_AGE (seconds).
+func LoadCORSOptions() (CORSOptions, error) {
+	if path := os.Getenv("CORS_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return CORSOptions{}, fmt.Errorf("reading CORS config: %w", err)
+		}
+		var options CORSOptions
+		if err := json.Unmarshal(data, &options); err != nil {
+			return CORSOptions{}, fmt.Errorf("parsing CORS config %s: %w", path, err)
+		}
+		return options, nil
+	}
+
+	options := CORSOptions{
+		AllowedOrigins: envList("CORS_ALLOWED_ORIGINS"),
+		AllowedMethods: envList("CORS_ALLOWED_METHODS"),
+		AllowedHeaders: envList("CORS_ALLOWED_HEADERS"),
+		ExposedHeaders: envList("CORS_EXPOSED_HEADERS"),
+	}
+	if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return CORSOptions{}, fmt.Errorf("CORS_ALLOW_CREDENTIALS: %w", err)
+		}
+		options.AllowCredentials = &allow
+	}
+	if v := os.Getenv("CORS_MAX_AGE"); v != "" {
+		maxAge, err := strconv.Atoi(v)
+		if err != nil {
+			return CORSOptions{}, fmt.Errorf("CORS_MAX_AGE: %w", err)
+		}
+		options.MaxAge = maxAge
+	}
+	return options, nil
+}
+
+func envList(name string) []string {
+	return commaList(os.Getenv(name))
+}
+
+func commaList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// corsPolicy - CORSOptions with the defaults filled in and the origins compiled
+type corsPolicy struct {
+	anyOrigin        bool
+	exact            []string
+	patterns         []*regexp.Regexp
+	methods          []string
+	headers          []string
+	anyHeader        bool
+	exposed          string
+	allowCredentials bool
+	maxAge           int
+}
+
+func (options CORSOptions) withDefaults() CORSOptions {
+	if options.AllowedOrigins == nil {
+		options.AllowedOrigins = AllowedOrigins
+	}
+	if options.AllowedMethods == nil {
+		options.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	if options.AllowedHeaders == nil {
+		options.AllowedHeaders = []string{"Content-Type", "Authorization"}
+	}
+	if options.ExposedHeaders == nil {
+		options.ExposedHeaders = []string{"Authorization", "Location", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"}
+	}
+	if options.AllowCredentials == nil {
+		allow := true
+		options.AllowCredentials = &allow
+	}
+	if options.MaxAge == 0 {
+		options.MaxAge = 3600
+	}
+	return options
+}
+
+// override - a route's options on top of the top-level ones
+func (options CORSOptions) override(route CORSOptions) CORSOptions {
+	if route.AllowedOrigins != nil {
+		options.AllowedOrigins = route.AllowedOrigins
+	}
+	if route.AllowedMethods != nil {
+		options.AllowedMethods = route.AllowedMethods
+	}
+	if route.AllowedHeaders != nil {
+		options.AllowedHeaders = route.AllowedHeaders
+	}
+	if route.ExposedHeaders != nil {
+		options.ExposedHeaders = route.ExposedHeaders
+	}
+	if route.AllowCredentials != nil {
+		options.AllowCredentials = route.AllowCredentials
+	}
+	if route.MaxAge != 0 {
+		options.MaxAge = route.MaxAge
+	}
+	return options
+}
 
-		origin := r.Header.Get("Origin")
+func compileCORSPolicy(options CORSOptions) (*corsPolicy, error) {
+	options = options.withDefaults()
+	policy := &corsPolicy{
+		exposed:          strings.Join(options.ExposedHeaders, ", "),
+		allowCredentials: *options.AllowCredentials,
+		maxAge:           options.MaxAge,
+	}
+	for _, method := range options.AllowedMethods {
+		policy.methods = append(policy.methods, strings.ToUpper(method))
+	}
+	for _, header := range options.AllowedHeaders {
+		policy.anyHeader = policy.anyHeader || header == "*"
+		policy.headers = append(policy.headers, http.CanonicalHeaderKey(header))
+	}
 
-		if IsOriginAllowed(origin){
-			w.Header().Set("Access-Control-Allow-Origin",origin)
-		 }else{
-			http.Error(w, "Not Allowed By CORS ❌", http.StatusForbidden)
-			return 
-		 }
+	for _, origin := range options.AllowedOrigins {
+		switch {
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.HasPrefix(origin, "^"):
+			re, err := regexp.Compile(origin)
+			if err != nil {
+				return nil, fmt.Errorf("CORS origin %q: %w", origin, err)
+			}
+			policy.patterns = append(policy.patterns, re)
+		case strings.Contains(origin, "*"):
+			// https://*.example.com - one or more subdomain labels, never the apex itself
+			scheme, host, ok := strings.Cut(origin, "://*.")
+			if !ok || strings.Contains(host, "*") {
+				return nil, fmt.Errorf("CORS origin %q: only a leading \"*.\" subdomain wildcard is supported", origin)
+			}
+			policy.patterns = append(policy.patterns,
+				regexp.MustCompile(`^`+regexp.QuoteMeta(scheme)+`://([a-z0-9-]+\.)+`+regexp.QuoteMeta(strings.ToLower(host))+`$`))
+		default:
+			policy.exact = append(policy.exact, strings.ToLower(strings.TrimSuffix(origin, "/")))
+		}
+	}
+	// "*" + credentials would hand every site the users' cookies/auth - browsers refuse it as well
+	if policy.anyOrigin && policy.allowCredentials {
+		return nil, fmt.Errorf("CORS: allowed origin \"*\" can't be combined with allow_credentials")
+	}
+	return policy, nil
+}
 
-		fmt.Println(origin)
-		w.Header().Set("Access-Control-Allow-Headers","Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Methods","GET, POST, PUT, PATCH, DELETE")
-		w.Header().Set("Access-Control-Allow-Credentials","true")
-		w.Header().Set("Access-Control-Expose-Headers","Authorization")
-		w.Header().Set("Access-Control-Max-Age","3600")
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.exact, origin) {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CorsMiddleware - the policy from CORS_CONFIG / the CORS_* env vars; a broken config stops the server
+func CorsMiddleware(next http.Handler) http.Handler {
+	options, err := LoadCORSOptions()
+	if err != nil {
+		panic(err)
+	}
+	return CorsMiddlewareWithOptions(options)(next)
+}
 
-		if r.Method == http.MethodOptions{
-			// Pre-flight check
-			return 
+func CorsMiddlewareWithOptions(options CORSOptions) func(http.Handler) http.Handler {
+	base, err := compileCORSPolicy(options)
+	if err != nil {
+		panic(err)
+	}
+	type routePolicy struct {
+		prefix string
+		policy *corsPolicy
+	}
+	var routes []routePolicy
+	for _, route := range options.Routes {
+		policy, err := compileCORSPolicy(options.override(route.CORSOptions))
+		if err != nil {
+			panic(fmt.Errorf("CORS route %s: %w", route.PathPrefix, err))
 		}
+		routes = append(routes, routePolicy{route.PathPrefix, policy})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := base
+			for _, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.prefix) {
+					policy = route.policy
+					break
+				}
+			}
+
+			// the response differs per Origin - shared caches must not mix them up
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// no Origin: same-origin GET, curl, server-to-server - CORS doesn't apply
+			if origin == "" || (!preflight && isSameOrigin(r, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !policy.allows(origin) {
+				if preflight {
+					http.Error(w, "Not Allowed By CORS ❌", http.StatusForbidden)
+					return
+				}
+				// no CORS headers - the browser won't let the page read the response
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if policy.anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if policy.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+				if !slices.Contains(policy.methods, method) {
+					http.Error(w, "Method Not Allowed By CORS ❌", http.StatusForbidden)
+					return
+				}
+				requested := commaList(r.Header.Get("Access-Control-Request-Headers"))
+				for _, header := range requested {
+					if !policy.anyHeader && !slices.Contains(policy.headers, http.CanonicalHeaderKey(header)) {
+						http.Error(w, "Header "+header+" Not Allowed By CORS ❌", http.StatusForbidden)
+						return
+					}
+				}
+
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.methods, ", "))
+				if policy.anyHeader {
+					w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", ")) // "*" doesn't cover Authorization
+				} else {
+					w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.headers, ", "))
+				}
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(policy.maxAge))
+				w.WriteHeader(http.StatusNoContent) // Pre-flight check
+				return
+			}
+
+			if policy.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		next.ServeHTTP(w,r)
-	})
+// isSameOrigin - browsers send Origin on same-origin POSTs too; those aren't CORS requests
+func isSameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return u.Scheme == scheme && strings.EqualFold(u.Host, r.Host)
 }
 
-func IsOriginAllowed(origin string)bool{
+func IsOriginAllowed(origin string) bool {
 	return slices.Contains(AllowedOrigins, origin)
 }
 
@@ -57,4 +340,4 @@ func IsOriginAllowed(origin string)bool{
 // 		}
 // 	}
 // 	return false
-// }
\ No newline at end of file
+// }
@@ -1,60 +1,109 @@
 package middlewares
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
 )
 
 // to prevent hpp-attacks( ) 🛡️
+// A parameter sent twice (?sortby=a&sortby=b, or "email" twice in a JSON object) is resolved by the Policy -
+// unless it's whitelisted, then all its values are kept for the handler.
+
+// What to do with a repeated, non-whitelisted parameter
+const (
+	HPPFirst  = "first"  // keep the first value
+	HPPLast   = "last"   // keep the last value
+	HPPReject = "reject" // 400 Bad Request
+)
+
+const maxHPPBodySize = 10 << 20 // 10MB, same as utils.DecodeBody
 
-type HPPOptions struct{
-	CheckQuery bool
-	CheckBody bool
+type HPPOptions struct {
+	CheckQuery                  bool
+	CheckBody                   bool
 	CheckBodyOnlyForContentType string
-	WhiteList []string
+	CheckJSONBody               bool     // duplicate keys in JSON objects (at any depth)
+	WhiteList                   []string // may repeat in query/form; JSON objects can't hold two values for a key, so it doesn't apply there
+	Policy                      string   // HPPFirst (default), HPPLast or HPPReject
 }
 
-func HppMiddleware(options HPPOptions)func(http.Handler)http.Handler{
-return func(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if options.CheckBody && r.Method== http.MethodPost && isCorrectContentType(r, options.CheckBodyOnlyForContentType){
-			// filter the body-params
-			filterBodyParams(r,options.WhiteList)
-		}
-		if options.CheckQuery && r.URL.Query() !=nil {
-			// filter the query-params
-			filterQueryParams(r,options.WhiteList)
-		}
-		next.ServeHTTP(w,r)
-	})
-  }
+func HppMiddleware(options HPPOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if options.CheckQuery && r.URL.RawQuery != "" {
+				// filter the query-params
+				if err := filterQueryParams(r, options); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if options.CheckBody && r.Method == http.MethodPost && isCorrectContentType(r, options.CheckBodyOnlyForContentType) {
+				// filter the body-params
+				if err := filterBodyParams(r, options); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if options.CheckJSONBody && r.Body != nil && r.ContentLength != 0 && isJSONBody(r) {
+				status, err := filterJSONBody(r, options.Policy)
+				if err != nil {
+					http.Error(w, err.Error(), status)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-func isCorrectContentType(r *http.Request, contentType string)bool{
-return strings.Contains(r.Header.Get("Content-Type"), contentType)
+func isCorrectContentType(r *http.Request, contentType string) bool {
+	return strings.Contains(r.Header.Get("Content-Type"), contentType)
 }
 
-func filterBodyParams(r *http.Request, whitelist []string){
-	err:=r.ParseForm()
-	if err != nil {
-		fmt.Println("⚠️ ERROR:",err)
-		return
-	}
+// no Content-Type is read as JSON by utils.DecodeBody as well
+func isJSONBody(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return contentType == "" || utils.NormalizeMediaType(contentType) == utils.MediaJSON
+}
 
-	for k,v:=range r.Form{
-		if len(v)>1{
-			r.Form.Set(k,v[0]) // first value
-			// r.Form.Set(k,v[len(v)-1]) // last value
+// resolve applies the policy to every repeated, non-whitelisted key of values
+func resolve(values map[string][]string, options HPPOptions) error {
+	for k, v := range values {
+		if len(v) < 2 || isWhiteListed(k, options.WhiteList) {
+			continue
 		}
-		if isWhiteListed(k,whitelist){
-			delete(r.Form,k)
+		switch options.Policy {
+		case HPPReject:
+			return fmt.Errorf("parameter %q must not be repeated ⚠️", k)
+		case HPPLast:
+			values[k] = v[len(v)-1:] // last value
+		default:
+			values[k] = v[:1] // first value
 		}
 	}
+	return nil
+}
+
+func filterBodyParams(r *http.Request, options HPPOptions) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("invalid form body ⚠️")
+	}
+	if err := resolve(r.PostForm, options); err != nil {
+		return err
+	}
+	return resolve(r.Form, options)
 }
 
-func isWhiteListed(param string, whiteList []string)bool{
+func isWhiteListed(param string, whiteList []string) bool {
 	return slices.Contains(whiteList, param)
 }
 
@@ -68,18 +117,99 @@ func isWhiteListed(param string, whiteList []string)bool{
 // 	return false
 // }
 
-func filterQueryParams(r *http.Request, whitelist []string){
-	query:=r.URL.Query()
-	for k,v:=range query{
-		if len(v)>1{
-			query.Set(k,v[0])
-		}
-		if isWhiteListed(k,whitelist){
-			query.Del(k)
-		}
+func filterQueryParams(r *http.Request, options HPPOptions) error {
+	query := r.URL.Query()
+	if err := resolve(query, options); err != nil {
+		return err
 	}
-	r.URL.RawQuery=query.Encode()
+	r.URL.RawQuery = query.Encode()
+	return nil
 }
 
+// filterJSONBody - encoding/json silently keeps the last of two equal keys, so {"email": "a", "email": "b"}
+// (or {"email": "a", "EMAIL": "b"} - keys match fields case-insensitively) would slip past validation of
+// the first one. The body is resolved by the policy and put back for the handler.
+// XML and MessagePack bodies are checked by utils.DecodeBody itself, which rejects repeated keys.
+func filterJSONBody(r *http.Request, policy string) (int, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxHPPBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("ERROR reading request body ⚠️")
+	}
+	if len(data) > maxHPPBodySize {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request body too large ⚠️")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var duplicates []string
+	tree, err := dedupeJSON(dec, policy == HPPLast, "", &duplicates)
+	if err != nil || len(duplicates) == 0 {
+		return 0, nil // not JSON after all - the handler reports that itself
+	}
+	if policy == HPPReject {
+		return http.StatusBadRequest, fmt.Errorf("duplicate keys in JSON body: %s ⚠️", strings.Join(duplicates, ", "))
+	}
 
+	resolved, err := json.Marshal(tree)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body ⚠️")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(resolved))
+	r.ContentLength = int64(len(resolved))
+	r.Header.Set("Content-Length", strconv.Itoa(len(resolved)))
+	return 0, nil
+}
 
+// dedupeJSON parses the next value, keeping the first (or last) of repeated object keys and noting their paths
+func dedupeJSON(dec *json.Decoder, keepLast bool, path string, duplicates *[]string) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &utils.Object{Values: map[string]any{}}
+		keys := map[string]string{} // utils.FoldKey -> the key as first sent
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			val, err := dedupeJSON(dec, keepLast, keyPath, duplicates)
+			if err != nil {
+				return nil, err
+			}
+			if first, dup := keys[utils.FoldKey(key)]; dup {
+				*duplicates = append(*duplicates, keyPath)
+				if keepLast {
+					obj.Values[first] = val
+				}
+				continue
+			}
+			keys[utils.FoldKey(key)] = key
+			obj.Keys = append(obj.Keys, key)
+			obj.Values[key] = val
+		}
+		_, err := dec.Token() // '}'
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for i := 0; dec.More(); i++ {
+			val, err := dedupeJSON(dec, keepLast, path+"["+strconv.Itoa(i)+"]", duplicates)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err := dec.Token() // ']'
+		return list, err
+	}
+	return tok, nil
+}
@@ -1,52 +1,212 @@
 package middlewares
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
-	"sync"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/auth"
 )
 
+// Rate limiting: every client gets Limit requests per Window - as a token bucket in memory (a request takes
+// one token, the bucket refills evenly over Window, bursts up to Limit are fine) or as a sliding-window
+// counter in the database when the replicas have to share the quota (see rate_limiter.store.go).
+// Counters are per client AND per policy, so a tight limit on /import doesn't eat into the default one.
+
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitRoute applies Policy to requests whose path starts with PathPrefix (and Method, if set) - first match wins
+type RateLimitRoute struct {
+	Method     string
+	PathPrefix string
+	Policy     RateLimitPolicy
+}
+
+// What a client is
+const (
+	RateLimitByIP     = "ip"      // the client IP
+	RateLimitByAPIKey = "api_key" // the X-API-Key header if it's one of APIKeys, the IP otherwise
+	RateLimitByUser   = "user"    // the exec/teacher of a valid bearer token, else like api_key
+)
+
+type RateLimiterOptions struct {
+	Default        RateLimitPolicy
+	Routes         []RateLimitRoute
+	KeyBy          string         // RateLimitBy..., ip by default
+	APIKeys        []string       // the valid X-API-Key values - an unknown key is no key (else a new key per request = a new quota)
+	TrustedProxies []string       // CIDRs/IPs of our own proxies - only they're believed about X-Forwarded-For
+	Store          RateLimitStore // in-memory by default; use the SQL store to share limits between replicas
+}
+
 type rateLimiter struct {
-	mu  sync.Mutex
-	visitors  map[string]int
-	limit  int
-	resetTime  time.Duration
+	options RateLimiterOptions
+	proxies []netip.Prefix
+	apiKeys map[string]bool // hashes of options.APIKeys
 }
 
-func NewRateLimiter(limit int, resetTime time.Duration)*rateLimiter{
-	rl:= &rateLimiter{
-		visitors: make(map[string]int),
-		limit: limit,
-		resetTime: resetTime,
+// NewRateLimiter - limit requests per resetTime for every client IP
+func NewRateLimiter(limit int, resetTime time.Duration) *rateLimiter {
+	return NewRateLimiterWithOptions(RateLimiterOptions{Default: RateLimitPolicy{Limit: limit, Window: resetTime}})
+}
+
+func NewRateLimiterWithOptions(options RateLimiterOptions) *rateLimiter {
+	if options.Store == nil {
+		options.Store = NewMemoryRateLimitStore()
+	}
+	rl := &rateLimiter{options: options, proxies: ParseTrustedProxies(options.TrustedProxies), apiKeys: map[string]bool{}}
+	for _, key := range options.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			rl.apiKeys[hashAPIKey(key)] = true
+		}
 	}
-	// start the reset-routine
-	go rl.resetVisitorCount() // runs in the background
 	return rl
 }
 
-func (rl *rateLimiter) resetVisitorCount(){
-	for {
-		time.Sleep(rl.resetTime)
-		rl.mu.Lock()
-		rl.visitors = make(map[string]int)
-		rl.mu.Unlock()
+// hashAPIKey - the keys themselves aren't kept in memory (or in the rate_limits table)
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseTrustedProxies - "10.0.0.0/8", "192.168.1.10"; invalid entries are logged and skipped
+func ParseTrustedProxies(list []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(item); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			fmt.Printf("⚠️ invalid trusted proxy %q - skipped\n", item)
+		}
 	}
+	return prefixes
 }
 
-// Now, create the middleware( )
-func (rl *rateLimiter) RateLimiterMiddleware(next http.Handler)http.Handler{
-	return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request){
-		rl.mu.Lock()
-		defer rl.mu.Unlock()
-		visitorIP:=r.RemoteAddr // minimal and simple for now.
-		rl.visitors[visitorIP]++
-		fmt.Printf("⚠️ Visitor Count from %v is %v\n",visitorIP,rl.visitors[visitorIP])
+func (rl *rateLimiter) RateLimiterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policyName, policy := rl.policyFor(r)
+		if policy.Limit <= 0 || policy.Window <= 0 {
+			next.ServeHTTP(w, r) // no limit on this route
+			return
+		}
+
+		result, err := rl.options.Store.Take(policyName+"|"+rl.clientKey(r), policy, time.Now())
+		if err != nil {
+			// a broken store mustn't take the API down with it - let the request through
+			fmt.Println("⚠️ rate-limit store:", err)
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if rl.visitors[visitorIP] > rl.limit{
-			http.Error(w, "Too Many Requests ⚠️",http.StatusTooManyRequests)
-			return 
+		// https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			http.Error(w, "Too Many Requests ⚠️", http.StatusTooManyRequests)
+			return
 		}
-		next.ServeHTTP(w,r)
+		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+func (rl *rateLimiter) policyFor(r *http.Request) (string, RateLimitPolicy) {
+	for i, route := range rl.options.Routes {
+		if (route.Method == "" || route.Method == r.Method) && strings.HasPrefix(r.URL.Path, route.PathPrefix) {
+			return "route" + strconv.Itoa(i), route.Policy
+		}
+	}
+	return "default", rl.options.Default
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// clientKey - only ever a verified credential: anything a client can make up (a random key per request)
+// would give it a fresh quota every time, so an unknown key or token counts as none - the IP.
+func (rl *rateLimiter) clientKey(r *http.Request) string {
+	switch rl.options.KeyBy {
+	case RateLimitByUser:
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if p, err := auth.Verify(token); err == nil {
+				return "user:" + p.Role + ":" + strconv.Itoa(p.ID)
+			}
+		}
+		fallthrough
+	case RateLimitByAPIKey:
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			if hash := hashAPIKey(apiKey); rl.apiKeys[hash] {
+				return "key:" + hash[:16]
+			}
+		}
+	}
+	return "ip:" + ClientIP(r, rl.proxies)
+}
+
+// ClientIP - the address the request came from, without the port. When that's one of our trusted proxies,
+// X-Forwarded-For is walked from the right (the end our proxies appended to) to the first address that isn't
+// a trusted proxy; everything left of it could have been sent by the client.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remote := parseIP(r.RemoteAddr)
+	if !remote.IsValid() {
+		return r.RemoteAddr
+	}
+	if !isTrusted(remote, trustedProxies) {
+		return remote.String()
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIP(strings.TrimSpace(hops[i]))
+		if !hop.IsValid() {
+			break // garbage - stop at the last address we could trust
+		}
+		client = hop
+		if !isTrusted(hop, trustedProxies) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func parseIP(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// RateLimitStore keeps the per-client counters. The in-memory store is exact but per process -
+// with several replicas behind a load balancer every one of them hands out the full quota,
+// the SQL store shares the counters through the database instead.
+type RateLimitStore interface {
+	// Take counts a request of key against policy
+	Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // when denied: until the next request would be allowed
+	Reset      time.Duration // until the full quota is available again
+}
+
+//! In-memory token buckets
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	stop    chan struct{}
+}
+
+func NewMemoryRateLimitStore() *memoryRateLimitStore {
+	s := &memoryRateLimitStore{buckets: make(map[string]*bucket), stop: make(chan struct{})}
+	// start the eviction-routine
+	go s.evictIdle() // runs in the background
+	return s
+}
+
+// Stop ends the eviction-routine
+func (s *memoryRateLimitStore) Stop() {
+	close(s.stop)
+}
+
+// a bucket that's been idle for a whole window is full again - same as not having one
+func (s *memoryRateLimitStore) evictIdle() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, b := range s.buckets {
+				if now.Sub(b.last) >= b.window {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Take removes a token from key's bucket if there's one. The lock is only held for the arithmetic,
+// never while the request is being served.
+func (s *memoryRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	perToken := policy.Window / time.Duration(policy.Limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), last: now, window: policy.Window}
+		s.buckets[key] = b
+	}
+	b.tokens = min(float64(policy.Limit), b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	var result RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(policy.Limit) - b.tokens) * float64(perToken))
+	return result, nil
+}
+
+//! Shared counters in the database (schema/011_rate_limits.sql)
+
+// sqlRateLimitStore is a sliding-window counter: the hits of the current fixed window plus the previous
+// window's hits weighted by how much of it still overlaps the last Window. One atomic upsert per request.
+// Denied requests are counted too, so a client that keeps hammering stays limited.
+// Windows are aligned on the replicas' clocks, which NTP keeps close enough.
+type sqlRateLimitStore struct {
+	db   *sql.DB
+	stop chan struct{}
+}
+
+// NewSQLRateLimitStore - db is a long-lived pool (not closed by the store), every request takes a connection from it
+func NewSQLRateLimitStore(db *sql.DB) *sqlRateLimitStore {
+	s := &sqlRateLimitStore{db: db, stop: make(chan struct{})}
+	go s.purgeExpired()
+	return s
+}
+
+// Stop ends the purge-routine
+func (s *sqlRateLimitStore) Stop() {
+	close(s.stop)
+}
+
+func (s *sqlRateLimitStore) purgeExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sqlconnect.PurgeRateLimitsDbHandler(s.db)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *sqlRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	windowStart := now.Truncate(policy.Window)
+	current, previous, err := sqlconnect.HitRateLimitDbHandler(s.db, key, windowStart, policy.Window)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate-limit counter %s: %w", key, err)
+	}
+	return slidingWindow(policy, now.Sub(windowStart), current, previous), nil
+}
+
+// slidingWindow - current includes the request being decided on
+func slidingWindow(policy RateLimitPolicy, elapsed time.Duration, current, previous int) RateLimitResult {
+	window, limit := float64(policy.Window), float64(policy.Limit)
+	overlap := 1 - float64(elapsed)/window // share of the previous window still inside the last Window
+	estimate := float64(previous)*overlap + float64(current)
+
+	result := RateLimitResult{
+		Allowed:   estimate <= limit,
+		Remaining: max(0, policy.Limit-int(math.Ceil(estimate))),
+		Reset:     policy.Window - elapsed, // the previous window's hits are gone by then...
+	}
+	if current > 0 {
+		result.Reset += policy.Window // ...the current one's a window later
+	}
+	if !result.Allowed {
+		// wait until the weighted previous hits have dropped enough for one more request;
+		// if the current window alone is over the limit, that's only in the next window
+		var wait float64
+		if float64(current)+1 > limit {
+			wait = window - float64(elapsed) + window*(1-(limit-1)/float64(current))
+		} else {
+			wait = window*(1-(limit-float64(current)-1)/float64(previous)) - float64(elapsed)
+		}
+		result.RetryAfter = time.Duration(max(wait, float64(time.Second)))
+	}
+	return result
+}
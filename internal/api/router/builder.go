@@ -0,0 +1,210 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// Builder registers routes on an http.ServeMux with middleware per group and per route.
+// A route's chain is: the middleware of every group from the root down to its own, in Use order -
+// the first one is the outermost (sees the request first). Use applies to all routes of its group,
+// no matter whether it's called before or after they were added, so the order is always the same.
+//
+//	r := New()
+//	r.Use(MW("security", middlewares.SecurityHeaders))
+//	teachers := r.Group("/teachers")
+//	teachers.HandleFunc("GET", handlers.GetTeachersHandler)                       // GET /teachers
+//	teachers.With(MW("auth", middlewares.AuthMiddleware)).HandleFunc("POST", ...) // auth on writes only
+//	r.Skip("compression").HandleFunc("GET /events/stream", ...)                   // no gzip on a stream
+type Builder struct {
+	parent      *Builder
+	prefix      string
+	middlewares []Middleware
+	skip        []string
+	tree        *tree
+}
+
+// Middleware - the name is what Skip refers to and what Routes shows
+type Middleware struct {
+	Name string
+	Wrap utils.Middleware
+}
+
+func MW(name string, wrap utils.Middleware) Middleware {
+	return Middleware{Name: name, Wrap: wrap}
+}
+
+type tree struct {
+	mux    *http.ServeMux
+	routes []*route
+	built  bool
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+	group   *Builder
+}
+
+// RouteInfo - a registered route and its effective middleware chain, outermost first
+type RouteInfo struct {
+	Pattern string
+	Chain   []string
+	Handler string
+}
+
+func New() *Builder {
+	return &Builder{tree: &tree{mux: http.NewServeMux()}}
+}
+
+// Use adds middleware to every route of this group (and its sub-groups)
+func (b *Builder) Use(mws ...Middleware) *Builder {
+	b.middlewares = append(b.middlewares, mws...)
+	return b
+}
+
+// Group - routes added to it get prefix in front of their path ("GET /{id}" -> "GET /teachers/{id}")
+func (b *Builder) Group(prefix string) *Builder {
+	return &Builder{parent: b, prefix: b.prefix + strings.TrimSuffix(prefix, "/"), tree: b.tree}
+}
+
+// With - the same group with extra middleware for the routes added through it
+func (b *Builder) With(mws ...Middleware) *Builder {
+	return b.Group("").Use(mws...)
+}
+
+// Skip - the same group without the named (inherited) middleware for the routes added through it
+func (b *Builder) Skip(names ...string) *Builder {
+	g := b.Group("")
+	g.skip = names
+	return g
+}
+
+// Handle - pattern is "[METHOD] [/path]", relative to the group: "GET" alone is the group's own path
+func (b *Builder) Handle(pattern string, handler http.Handler) {
+	if b.tree.built {
+		panic("router: route " + pattern + " added after Build")
+	}
+	method, path, found := strings.Cut(pattern, " ")
+	if !found && strings.HasPrefix(pattern, "/") {
+		method, path = "", pattern
+	}
+	full := b.prefix + path
+	if full == "" {
+		full = "/"
+	}
+	if method != "" {
+		full = method + " " + full
+	}
+	b.tree.routes = append(b.tree.routes, &route{pattern: full, handler: handler, group: b})
+}
+
+func (b *Builder) HandleFunc(pattern string, handler http.HandlerFunc) {
+	b.Handle(pattern, handler)
+}
+
+// chain - the effective middleware of a group, outermost first
+func (b *Builder) chain() []Middleware {
+	var groups []*Builder
+	for g := b; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+	slices.Reverse(groups)
+
+	var chain, skipped []string
+	var mws []Middleware
+	for _, g := range groups {
+		skipped = append(skipped, g.skip...)
+	}
+	for _, g := range groups {
+		for _, mw := range g.middlewares {
+			if slices.Contains(skipped, mw.Name) {
+				continue
+			}
+			if slices.Contains(chain, mw.Name) {
+				panic(fmt.Sprintf("router: middleware %q is applied twice", mw.Name))
+			}
+			chain = append(chain, mw.Name)
+			mws = append(mws, mw)
+		}
+	}
+	return mws
+}
+
+// Build wraps every route in its chain and registers it on the mux. Requests no route matches
+// still go through the root group's middleware (404).
+func (b *Builder) Build() http.Handler {
+	root := b.root()
+	t := root.tree
+	if t.built {
+		return t.mux
+	}
+	t.built = true
+
+	hasCatchAll := slices.ContainsFunc(t.routes, func(rt *route) bool { return rt.pattern == "/" })
+	if !hasCatchAll {
+		t.routes = append(t.routes, &route{pattern: "/", handler: http.NotFoundHandler(), group: root})
+	}
+
+	for _, rt := range t.routes {
+		var wraps []utils.Middleware
+		for _, mw := range rt.group.chain() {
+			wraps = append(wraps, mw.Wrap)
+		}
+		t.mux.Handle(rt.pattern, utils.ApplyMiddlewares(rt.handler, wraps...))
+	}
+	return t.mux
+}
+
+func (b *Builder) root() *Builder {
+	for b.parent != nil {
+		b = b.parent
+	}
+	return b
+}
+
+// ServeHTTP - the built mux (Build runs on the first request if it hasn't yet)
+func (b *Builder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.Build().ServeHTTP(w, r)
+}
+
+// Routes lists every route with its effective chain, in registration order
+func (b *Builder) Routes() []RouteInfo {
+	var infos []RouteInfo
+	for _, rt := range b.root().tree.routes {
+		info := RouteInfo{Pattern: rt.pattern, Chain: []string{}, Handler: handlerName(rt.handler)}
+		for _, mw := range rt.group.chain() {
+			info.Chain = append(info.Chain, mw.Name)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Dump writes the routing table: pattern, chain and handler
+func (b *Builder) Dump(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, info := range b.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\n", info.Pattern, strings.Join(append(info.Chain, info.Handler), " > "))
+	}
+	tw.Flush()
+}
+
+// handlerName - "handlers.GetTeachersHandler" for a func, the type name otherwise
+func handlerName(h http.Handler) string {
+	if fn, ok := h.(http.HandlerFunc); ok {
+		if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+			name := f.Name()
+			return name[strings.LastIndex(name, "/")+1:]
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recorder hands out middleware that note their name in ran when a request passes through them
+type recorder struct {
+	ran []string
+}
+
+func (rec *recorder) mw(name string) Middleware {
+	return MW(name, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec.ran = append(rec.ran, name)
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serve sends one request and returns the middleware it went through and the status
+func (rec *recorder) serve(t *testing.T, h http.Handler, method, path string) ([]string, int) {
+	t.Helper()
+	rec.ran = nil
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return rec.ran, w.Code
+}
+
+func chainOf(t *testing.T, b *Builder, pattern string) []string {
+	t.Helper()
+	for _, info := range b.Routes() {
+		if info.Pattern == pattern {
+			return info.Chain
+		}
+	}
+	t.Fatalf("route %s not registered", pattern)
+	return nil
+}
+
+func TestChainRunsOutermostFirst(t *testing.T) {
+	rec := &recorder{}
+	r := New()
+	r.Use(rec.mw("root"))
+	teachers := r.Group("/teachers").Use(rec.mw("group"))
+	teachers.With(rec.mw("route")).HandleFunc("POST", okHandler)
+	teachers.Use(rec.mw("late")) // after the route was added - still applies, in Use order
+
+	want := []string{"root", "group", "late", "route"}
+	ran, code := rec.serve(t, r, "POST", "/teachers")
+	if code != http.StatusNoContent {
+		t.Fatalf("POST /teachers: status %d", code)
+	}
+	if !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if chain := chainOf(t, r, "POST /teachers"); !slices.Equal(chain, want) {
+		t.Errorf("Routes() shows %v, but the request ran %v", chain, want)
+	}
+}
+
+func TestSkipAndWithOnlyAffectTheirRoutes(t *testing.T) {
+	rec := &recorder{}
+	r := New()
+	r.Use(rec.mw("security"), rec.mw("compression"))
+	events := r.Group("/events")
+	events.HandleFunc("GET", okHandler)
+	events.Skip("compression").HandleFunc("GET /stream", okHandler)
+	events.With(rec.mw("auth")).HandleFunc("POST", okHandler)
+
+	tests := []struct {
+		method, path string
+		want         []string
+	}{
+		{"GET", "/events", []string{"security", "compression"}},
+		{"GET", "/events/stream", []string{"security"}},
+		{"POST", "/events", []string{"security", "compression", "auth"}},
+	}
+	for _, tt := range tests {
+		if ran, _ := rec.serve(t, r, tt.method, tt.path); !slices.Equal(ran, tt.want) {
+			t.Errorf("%s %s ran %v, want %v", tt.method, tt.path, ran, tt.want)
+		}
+	}
+}
+
+func TestUnmatchedRequestsGetTheRootChain(t *testing.T) {
+	rec := &recorder{}
+	r := New()
+	r.Use(rec.mw("security"))
+	r.Group("/teachers").Use(rec.mw("auth")).HandleFunc("GET", okHandler)
+
+	ran, code := rec.serve(t, r, "GET", "/nowhere")
+	if code != http.StatusNotFound {
+		t.Errorf("GET /nowhere: status %d, want 404", code)
+	}
+	if !slices.Equal(ran, []string{"security"}) {
+		t.Errorf("GET /nowhere ran %v, want only the root chain", ran)
+	}
+	if chain := chainOf(t, r, "/"); !slices.Equal(chain, []string{"security"}) {
+		t.Errorf("catch-all chain %v", chain)
+	}
+}
+
+func TestOwnCatchAllIsKept(t *testing.T) {
+	r := New()
+	r.HandleFunc("/", okHandler)
+	r.Build()
+
+	if routes := r.Routes(); len(routes) != 1 || routes[0].Handler != "router.okHandler" {
+		t.Fatalf("routes %+v, want only the own catch-all", routes)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/nowhere", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("GET /nowhere: status %d, want the own handler's 204", w.Code)
+	}
+}
+
+func mustPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		got, _ := recover().(string)
+		if !strings.Contains(got, want) {
+			t.Errorf("panic %q, want %q", got, want)
+		}
+	}()
+	f()
+}
+
+func TestMiddlewareAppliedTwicePanics(t *testing.T) {
+	rec := &recorder{}
+	r := New()
+	r.Use(rec.mw("auth"))
+	r.Group("/teachers").With(rec.mw("auth")).HandleFunc("POST", okHandler)
+
+	mustPanic(t, `middleware "auth" is applied twice`, func() { r.Build() })
+}
+
+func TestRouteAfterBuildPanics(t *testing.T) {
+	r := New()
+	r.Build()
+	mustPanic(t, "route GET /late added after Build", func() { r.HandleFunc("GET /late", okHandler) })
+}
+
+func TestDump(t *testing.T) {
+	rec := &recorder{}
+	r := New()
+	r.Use(rec.mw("security"))
+	teachers := r.Group("/teachers")
+	teachers.HandleFunc("GET", okHandler)
+	teachers.With(rec.mw("auth")).Handle("DELETE /{id}", http.RedirectHandler("/teachers", http.StatusSeeOther))
+	r.Build()
+
+	var out strings.Builder
+	r.Dump(&out)
+	want := "" +
+		"GET /teachers          security > router.okHandler\n" +
+		"DELETE /teachers/{id}  security > auth > *http.redirectHandler\n" +
+		"/                      security > http.NotFound\n"
+	if out.String() != want {
+		t.Errorf("Dump:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -1,31 +1,189 @@
 package router
 
 import (
-	"net/http"
-
 	"github.com/iamskyy111/go-rest-api/internal/api/handlers"
+	"github.com/iamskyy111/go-rest-api/internal/api/middlewares"
 )
 
-func Router() *http.ServeMux{
-mux:= http.NewServeMux()
+// Router - every route of the API. global is the server-wide chain (outermost first), the groups/routes add theirs:
+// Router(...).Dump(os.Stdout) prints the effective chain of each route.
+func Router(global ...Middleware) *Builder{
+r:= New()
+r.Use(global...)
+
+// streams: a compressor can't hold back an event-stream or a hijacked websocket
+streams:= r.Skip("compression")
+// every group adds its writes (POST/PUT/PATCH/DELETE) through <group>Writes - reads stay public
+auth:= MW("auth", middlewares.AuthMiddleware)
 
-mux.HandleFunc("/", handlers.RootHandler )
+r.HandleFunc("/", handlers.RootHandler )
 
 //! Teachers Handlers()
-mux.HandleFunc("GET /teachers", handlers.GetTeachersHandler)
-mux.HandleFunc("POST /teachers", handlers.AddTeachersHandler)
-mux.HandleFunc("PUT /teachers", handlers.UpdateTeacherHandler)
-mux.HandleFunc("PATCH /teachers", handlers.PatchTeachersHandler)
-mux.HandleFunc("DELETE /teachers", handlers.DeleteTeachersHandler)
+teachers:= r.Group("/teachers")
+teacherWrites:= teachers.With(auth)
+teachers.HandleFunc("GET", handlers.GetTeachersHandler)
+teacherWrites.HandleFunc("POST", handlers.AddTeachersHandler)
+teacherWrites.HandleFunc("PUT", handlers.UpdateTeacherHandler)
+teacherWrites.HandleFunc("PATCH", handlers.PatchTeachersHandler)
+teacherWrites.HandleFunc("DELETE", handlers.DeleteTeachersHandler)
+
+teachers.HandleFunc("GET /count", handlers.CountTeachersHandler)
+teachers.HandleFunc("GET /stats", handlers.GetTeacherStatsHandler)
+teacherWrites.HandleFunc("POST /import", handlers.ImportTeachersHandler)
+teachers.HandleFunc("GET /export", handlers.ExportTeachersHandler)
+
+teachers.HandleFunc("GET /{id}", handlers.GetTeacherHandler)
+teacherWrites.HandleFunc("PUT /{id}", handlers.UpdateTeacherHandler)
+teacherWrites.HandleFunc("PATCH /{id}", handlers.PatchTeacherHandler)
+teacherWrites.HandleFunc("DELETE /{id}", handlers.DeleteTeacherHandler)
+
+teachers.HandleFunc("GET /{id}/students", handlers.GetTeacherStudentsHandler)
+
+teachers.HandleFunc("GET /{id}/assignments", handlers.GetTeacherAssignmentsHandler)
+teacherWrites.HandleFunc("POST /{id}/assignments", handlers.AddTeacherAssignmentsHandler)
+teacherWrites.HandleFunc("DELETE /{id}/assignments/{assignmentId}", handlers.DeleteTeacherAssignmentHandler)
+
+teachers.HandleFunc("GET /{id}/timetable", handlers.GetTeacherTimetableHandler)
+
+//! Students Handlers()
+students:= r.Group("/students")
+studentWrites:= students.With(auth)
+students.HandleFunc("GET", handlers.GetStudentsHandler)
+studentWrites.HandleFunc("POST", handlers.AddStudentsHandler)
+studentWrites.HandleFunc("PATCH", handlers.PatchStudentsHandler)
+studentWrites.HandleFunc("DELETE", handlers.DeleteStudentsHandler)
+
+students.HandleFunc("GET /stats", handlers.GetStudentStatsHandler)
+studentWrites.HandleFunc("POST /import", handlers.ImportStudentsHandler)
+students.HandleFunc("GET /export", handlers.ExportStudentsHandler)
+
+students.HandleFunc("GET /{id}", handlers.GetStudentHandler)
+studentWrites.HandleFunc("PUT /{id}", handlers.UpdateStudentHandler)
+studentWrites.HandleFunc("PATCH /{id}", handlers.PatchStudentHandler)
+studentWrites.HandleFunc("DELETE /{id}", handlers.DeleteStudentHandler)
+
+students.HandleFunc("GET /{id}/teachers", handlers.GetStudentTeachersHandler)
+students.HandleFunc("GET /{id}/attendance", handlers.GetStudentAttendanceHandler)
+students.HandleFunc("GET /{id}/attendance/summary", handlers.GetStudentAttendanceSummaryHandler)
+students.HandleFunc("GET /{id}/report-card", handlers.GetReportCardHandler)
+students.HandleFunc("GET /{id}/enrollments", handlers.GetStudentEnrollmentsHandler)
+studentWrites.HandleFunc("POST /{id}/transfer", handlers.TransferStudentHandler)
+students.HandleFunc("GET /{id}/guardians", handlers.GetStudentGuardiansHandler)
+
+//! Guardians Handlers()
+guardians:= r.Group("/guardians")
+guardianWrites:= guardians.With(auth)
+guardians.HandleFunc("GET", handlers.GetGuardiansHandler)
+guardianWrites.HandleFunc("POST", handlers.AddGuardiansHandler)
+
+guardians.HandleFunc("GET /{id}", handlers.GetGuardianHandler)
+guardianWrites.HandleFunc("PUT /{id}", handlers.UpdateGuardianHandler)
+guardianWrites.HandleFunc("PATCH /{id}", handlers.PatchGuardianHandler)
+guardianWrites.HandleFunc("DELETE /{id}", handlers.DeleteGuardianHandler)
+
+//! Classes Handlers()
+classes:= r.Group("/classes")
+classWrites:= classes.With(auth)
+classes.HandleFunc("GET", handlers.GetClassesHandler)
+classWrites.HandleFunc("POST", handlers.AddClassesHandler)
+
+classes.HandleFunc("GET /{id}", handlers.GetClassHandler)
+classWrites.HandleFunc("PUT /{id}", handlers.UpdateClassHandler)
+classWrites.HandleFunc("PATCH /{id}", handlers.PatchClassHandler)
+classWrites.HandleFunc("DELETE /{id}", handlers.DeleteClassHandler)
+
+classes.HandleFunc("GET /{id}/students", handlers.GetClassStudentsHandler)
+classes.HandleFunc("GET /{id}/teachers", handlers.GetClassTeachersHandler)
+
+classes.HandleFunc("GET /{id}/attendance", handlers.GetClassAttendanceHandler)
+classWrites.HandleFunc("POST /{id}/attendance", handlers.MarkClassAttendanceHandler)
+classes.Skip("compression").With(auth).HandleFunc("GET /{id}/attendance/live", handlers.LiveAttendanceHandler)
+classes.HandleFunc("GET /{id}/attendance/summary", handlers.GetClassAttendanceSummaryHandler)
+classes.HandleFunc("GET /{id}/grade-distribution", handlers.GetGradeDistributionHandler)
+classes.HandleFunc("GET /{id}/timetable", handlers.GetClassTimetableHandler)
+classWrites.HandleFunc("POST /{id}/promote", handlers.PromoteClassHandler)
+
+//! Subjects Handlers()
+subjects:= r.Group("/subjects")
+subjectWrites:= subjects.With(auth)
+subjects.HandleFunc("GET", handlers.GetSubjectsHandler)
+subjectWrites.HandleFunc("POST", handlers.AddSubjectsHandler)
+
+subjects.HandleFunc("GET /{id}", handlers.GetSubjectHandler)
+subjectWrites.HandleFunc("PUT /{id}", handlers.UpdateSubjectHandler)
+subjectWrites.HandleFunc("PATCH /{id}", handlers.PatchSubjectHandler)
+subjectWrites.HandleFunc("DELETE /{id}", handlers.DeleteSubjectHandler)
+
+//! Assessments / Grades Handlers()
+assessments:= r.Group("/assessments")
+assessmentWrites:= assessments.With(auth)
+assessments.HandleFunc("GET", handlers.GetAssessmentsHandler)
+assessmentWrites.HandleFunc("POST", handlers.AddAssessmentsHandler)
+
+assessments.HandleFunc("GET /{id}", handlers.GetAssessmentHandler)
+assessmentWrites.HandleFunc("PATCH /{id}", handlers.PatchAssessmentHandler)
+assessmentWrites.HandleFunc("DELETE /{id}", handlers.DeleteAssessmentHandler)
+
+assessments.HandleFunc("GET /{id}/scores", handlers.GetScoresHandler)
+assessmentWrites.HandleFunc("POST /{id}/scores", handlers.AddScoresHandler)
+
+//! Timetable Handlers()
+timetable:= r.Group("/timetable")
+timetableWrites:= timetable.With(auth)
+timetable.HandleFunc("GET", handlers.GetTimetableHandler)
+timetableWrites.HandleFunc("POST", handlers.AddTimetableSlotsHandler)
+
+timetable.HandleFunc("GET /{id}", handlers.GetTimetableSlotHandler)
+timetableWrites.HandleFunc("PUT /{id}", handlers.UpdateTimetableSlotHandler)
+timetableWrites.HandleFunc("PATCH /{id}", handlers.PatchTimetableSlotHandler)
+timetableWrites.HandleFunc("DELETE /{id}", handlers.DeleteTimetableSlotHandler)
+
+//! Academic Years / Terms Handlers()
+academicYears:= r.Group("/academic-years")
+academicYearWrites:= academicYears.With(auth)
+academicYears.HandleFunc("GET", handlers.GetAcademicYearsHandler)
+academicYearWrites.HandleFunc("POST", handlers.AddAcademicYearHandler)
+
+academicYears.HandleFunc("GET /{id}", handlers.GetAcademicYearHandler)
+academicYearWrites.HandleFunc("PATCH /{id}", handlers.PatchAcademicYearHandler)
+academicYearWrites.HandleFunc("DELETE /{id}", handlers.DeleteAcademicYearHandler)
+
+academicYears.HandleFunc("GET /{id}/terms", handlers.GetTermsHandler)
+academicYearWrites.HandleFunc("POST /{id}/terms", handlers.AddTermsHandler)
+
+//! Webhooks Handlers()
+// webhook URLs, secrets and delivery logs are for authenticated clients only, reads included
+webhooks:= r.Group("/webhooks").Use(auth)
+webhooks.HandleFunc("GET", handlers.GetWebhooksHandler)
+webhooks.HandleFunc("POST", handlers.AddWebhookHandler)
+
+webhooks.HandleFunc("GET /{id}", handlers.GetWebhookHandler)
+webhooks.HandleFunc("PATCH /{id}", handlers.PatchWebhookHandler)
+webhooks.HandleFunc("DELETE /{id}", handlers.DeleteWebhookHandler)
+
+webhooks.HandleFunc("GET /{id}/deliveries", handlers.GetWebhookDeliveriesHandler)
+webhooks.HandleFunc("POST /{id}/deliveries/{deliveryId}/redeliver", handlers.RedeliverWebhookHandler)
+
+//! Events Handlers()
+streams.HandleFunc("GET /events/stream", handlers.EventStreamHandler)
+
+//! Jobs Handlers()
+jobs:= r.Group("/jobs")
+jobWrites:= jobs.With(auth)
+jobs.HandleFunc("GET", handlers.GetJobsHandler)
+jobs.HandleFunc("GET /{id}", handlers.GetJobHandler)
+jobWrites.HandleFunc("POST /{id}/cancel", handlers.CancelJobHandler)
+jobs.HandleFunc("GET /{id}/result", handlers.GetJobResultHandler)
+
+//! Auth Handlers() - the first exec token comes from cmd/token
+r.With(auth).HandleFunc("POST /auth/tokens", handlers.IssueTokenHandler)
 
-mux.HandleFunc("GET /teachers/{id}", handlers.GetTeacherHandler)
-mux.HandleFunc("PUT /teachers/{id}", handlers.UpdateTeacherHandler)
-mux.HandleFunc("PATCH /teachers/{id}", handlers.PatchTeacherHandler)
-mux.HandleFunc("DELETE /teachers/{id}", handlers.DeleteTeacherHandler)
+//! CSP violation reports
+r.HandleFunc("POST /csp-report", handlers.CSPReportHandler)
 
-mux.HandleFunc("/students", handlers.StudentsHandler)
-mux.HandleFunc("/execs", handlers.ExecsHandler)
+r.With(auth).HandleFunc("/execs", handlers.ExecsHandler)
 
-return mux
+r.Build()
+return r
 }
 
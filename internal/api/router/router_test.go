@@ -0,0 +1,44 @@
+package router
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// open - write routes that are public on purpose
+var open = map[string]string{
+	"/":                "root/404 catch-all, stores nothing",
+	"POST /csp-report": "browsers send CSP reports without credentials",
+}
+
+func TestWriteRoutesRequireAuth(t *testing.T) {
+	for _, info := range Router().Routes() {
+		method, path, found := strings.Cut(info.Pattern, " ")
+		if !found {
+			method, path = "", info.Pattern // no method - matches writes as well
+		}
+		isWrite := method == "" || method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+		if _, ok := open[info.Pattern]; ok || (!isWrite && !strings.HasPrefix(path, "/webhooks")) {
+			continue
+		}
+		if !slices.Contains(info.Chain, "auth") {
+			t.Errorf("%s (%s) has no auth: %s", info.Pattern, info.Handler, strings.Join(info.Chain, " > "))
+		}
+	}
+}
+
+func TestReadRoutesStayPublic(t *testing.T) {
+	public := []string{"GET /teachers", "GET /students/{id}", "GET /classes/{id}/attendance"}
+	for _, info := range Router().Routes() {
+		if slices.Contains(public, info.Pattern) {
+			if slices.Contains(info.Chain, "auth") {
+				t.Errorf("%s requires auth", info.Pattern)
+			}
+			public = slices.DeleteFunc(public, func(p string) bool { return p == info.Pattern })
+		}
+	}
+	if len(public) > 0 {
+		t.Errorf("routes not registered: %v", public)
+	}
+}
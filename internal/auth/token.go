@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access tokens of execs and teachers: HS256 JWTs signed with JWT_SECRET.
+// {"sub": "<exec/teacher-ID>", "role": "exec"|"teacher", "iat": ..., "exp": ...}
+//
+// Provisioning: the first exec token comes from the command line (go run ./cmd/token -role exec -id 1),
+// after that execs issue tokens through POST /auth/tokens - teachers can only renew their own there.
+
+// Roles
+const (
+	RoleExec    = "exec"
+	RoleTeacher = "teacher"
+)
+
+const (
+	DefaultTTL = 12 * time.Hour
+	MaxTTL     = 30 * 24 * time.Hour
+)
+
+var (
+	ErrNoSecret     = errors.New("JWT_SECRET is not set ⚠️")
+	ErrInvalidToken = errors.New("invalid token ⚠️")
+	ErrExpiredToken = errors.New("token expired ⚠️")
+)
+
+// Principal - who a request is made by
+type Principal struct {
+	Role string `json:"role"`
+	ID   int    `json:"id"`
+}
+
+type claims struct {
+	Sub  string `json:"sub"`
+	Role string `json:"role"`
+	Iat  int64  `json:"iat"`
+	Exp  int64  `json:"exp"`
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// read on every call - the .env file is loaded after init()
+func secret() ([]byte, error) {
+	s := os.Getenv("JWT_SECRET")
+	if s == "" {
+		return nil, ErrNoSecret
+	}
+	return []byte(s), nil
+}
+
+func sign(key []byte, signingInput string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Issue signs a token for p that's valid for ttl
+func Issue(p Principal, ttl time.Duration) (string, error) {
+	key, err := secret()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(claims{Sub: strconv.Itoa(p.ID), Role: p.Role, Iat: now.Unix(), Exp: now.Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + sign(key, signingInput), nil
+}
+
+// Verify checks the signature and expiry of token and returns whose it is
+func Verify(token string) (Principal, error) {
+	key, err := secret()
+	if err != nil {
+		return Principal{}, err
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	// the algorithm is fixed - never trust the token's own "alg"
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(rawHeader, &header) != nil || header.Alg != "HS256" {
+		return Principal{}, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(sign(key, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var c claims
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(rawClaims, &c) != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	if c.Exp == 0 || time.Now().Unix() >= c.Exp {
+		return Principal{}, ErrExpiredToken
+	}
+	id, err := strconv.Atoi(c.Sub)
+	if err != nil || id < 1 || (c.Role != RoleExec && c.Role != RoleTeacher) {
+		return Principal{}, ErrInvalidToken
+	}
+	return Principal{Role: c.Role, ID: id}, nil
+}
+
+type ctxKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext - the principal the auth middleware put on the request
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Domain events of teachers and students. The DB handlers write them to the outbox in the same
+// transaction as the change itself; the outbox dispatcher then hands them to the Sinks.
+
+// Event types
+const (
+	TeacherCreated = "teacher.created"
+	TeacherUpdated = "teacher.updated"
+	TeacherDeleted = "teacher.deleted"
+	StudentCreated = "student.created"
+	StudentUpdated = "student.updated"
+	StudentDeleted = "student.deleted"
+
+	StudentTransferred = "student.transferred"
+	StudentPromoted    = "student.promoted"
+)
+
+// Types - every event type, for validating subscriptions
+var Types = []string{TeacherCreated, TeacherUpdated, TeacherDeleted,
+	StudentCreated, StudentUpdated, StudentDeleted, StudentTransferred, StudentPromoted}
+
+type Event struct {
+	ID            string    `json:"id"` // unique, lets receivers drop duplicates
+	Type          string    `json:"type"`
+	AggregateType string    `json:"aggregate_type"` // teacher, student - events of one aggregate are delivered in order
+	AggregateID   int       `json:"aggregate_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Data          any       `json:"data"` // the teacher/student after the change, Deleted{} for deletes
+}
+
+// Deleted is the data of a *.deleted event - the record itself is gone
+type Deleted struct {
+	ID int `json:"id"`
+}
+
+// New - the aggregate type is the part of eventType before the dot
+func New(eventType string, aggregateID int, data any) Event {
+	aggregateType, _, _ := strings.Cut(eventType, ".")
+	return Event{ID: newID(), Type: eventType, AggregateType: aggregateType, AggregateID: aggregateID,
+		OccurredAt: time.Now().UTC(), Data: data}
+}
+
+// Decode reads an event back from its JSON, keeping Data as raw JSON
+func Decode(data []byte) (Event, error) {
+	var e struct {
+		Event
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Event{}, err
+	}
+	e.Event.Data = e.Data
+	return e.Event, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sink receives dispatched events. An error makes the dispatcher retry the event later
+// (delivery is at-least-once), so sinks should cope with seeing an event twice.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e Event) error
+}
+
+// LogSink writes every event to the log
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Send(ctx context.Context, e Event) error {
+	log.Printf("event %s %s %s/%d", e.ID, e.Type, e.AggregateType, e.AggregateID)
+	return nil
+}
+
+// BusSink hands events to the in-process subscribers (see Subscribe)
+type BusSink struct{}
+
+func (BusSink) Name() string { return "bus" }
+
+func (BusSink) Send(ctx context.Context, e Event) error {
+	Publish(e)
+	return nil
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers []func(Event)
+)
+
+// Subscribe registers fn for every event that reaches the BusSink
+func Subscribe(fn func(Event)) {
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Publish hands the event to all subscribers, in order. They run on the dispatcher's goroutine,
+// so they should hand off anything slow.
+func Publish(e Event) {
+	mu.RLock()
+	subs := subscribers
+	mu.RUnlock()
+	for _, fn := range subs {
+		fn(e)
+	}
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed is the outbox sink behind GET /events/stream: it numbers the events, keeps the last few
+// for Last-Event-ID resumption and fans them out to the connected streams.
+// Ids look like "<boot>-<seq>", so an id from before a restart is recognised as unknown.
+//
+// The feed lives in this process and only sees the events this replica's dispatcher relayed - with
+// several replicas the outbox rows are split between them (SKIP LOCKED), so a stream gets only part
+// of the events and can't resume on another replica (its ids are unknown there: a gap is reported).
+// Run the API as a single replica, or pin the streams to one, when clients rely on the full feed.
+type Feed struct {
+	mu        sync.Mutex
+	boot      string
+	seq       uint64
+	buf       []FeedEntry // ring buffer, buf[seq % len(buf)]
+	listeners map[chan FeedEntry]struct{}
+}
+
+type FeedEntry struct {
+	ID    string
+	Seq   uint64
+	Event Event
+}
+
+// listenerBuffer - a stream that falls this far behind is dropped, it resumes from the replay buffer
+const listenerBuffer = 64
+
+func NewFeed(size int) *Feed {
+	return &Feed{
+		boot:      newBootID(),
+		buf:       make([]FeedEntry, size),
+		listeners: map[chan FeedEntry]struct{}{},
+	}
+}
+
+// newBootID - replicas started in the same second still get different ones
+func newBootID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return strconv.FormatInt(time.Now().Unix(), 36) + hex.EncodeToString(b)
+}
+
+func (f *Feed) Name() string { return "sse" }
+
+func (f *Feed) Send(ctx context.Context, e Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	entry := FeedEntry{ID: fmt.Sprintf("%s-%d", f.boot, f.seq), Seq: f.seq, Event: e}
+	f.buf[f.seq%uint64(len(f.buf))] = entry
+	for ch := range f.listeners {
+		select {
+		case ch <- entry:
+		default:
+			delete(f.listeners, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Listen registers a stream. replay holds what happened after lastEventID; gap is true when
+// lastEventID is too old (or from before a restart) and events may have been missed.
+// live is closed when the stream is dropped for being too slow; cancel unregisters it.
+func (f *Feed) Listen(lastEventID string) (replay []FeedEntry, live <-chan FeedEntry, gap bool, cancel func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if lastEventID != "" {
+		boot, seqStr, _ := strings.Cut(lastEventID, "-")
+		seq, err := strconv.ParseUint(seqStr, 10, 64)
+		oldest := uint64(1)
+		if f.seq > uint64(len(f.buf)) {
+			oldest = f.seq - uint64(len(f.buf)) + 1
+		}
+		switch {
+		case err != nil || boot != f.boot || seq > f.seq:
+			gap = true
+		case seq+1 < oldest:
+			gap = true
+			seq = oldest - 1
+		}
+		if boot == f.boot && err == nil {
+			for s := max(seq+1, oldest); s <= f.seq; s++ {
+				replay = append(replay, f.buf[s%uint64(len(f.buf))])
+			}
+		}
+	}
+
+	ch := make(chan FeedEntry, listenerBuffer)
+	f.listeners[ch] = struct{}{}
+	cancel = func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.listeners[ch]; ok {
+			delete(f.listeners, ch)
+			close(ch)
+		}
+	}
+	return replay, ch, gap, cancel
+}
+
+// Match reports whether eventType matches one of the patterns: "*", "teacher.*" or an exact type
+func Match(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == eventType ||
+			(strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
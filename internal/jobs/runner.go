@@ -0,0 +1,311 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// In-process job runner backed by the jobs table - queued jobs outlive a restart,
+// and since workers claim with SKIP LOCKED any number of replicas can run it.
+
+// Handler does the actual work. report(done, total) publishes progress (total 0 = unknown);
+// ctx is cancelled when somebody cancels the job.
+type Handler func(ctx context.Context, job models.Job, report func(done, total int)) (result any, err error)
+
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// Permanent marks an error that won't go away by retrying (bad payload, validation, ...)
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent - the error (or one it wraps) came from Permanent, so the job isn't retried
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+type Runner struct {
+	Workers     int
+	MaxAttempts int
+	Poll        time.Duration // how often an idle runner looks for new jobs
+	Heartbeat   time.Duration // progress/cancel sync of a running job
+	StaleAfter  time.Duration // no heartbeat for this long => the worker died
+
+	mu       sync.Mutex
+	db       *sql.DB // long-lived - the runner polls all the time
+	handlers map[string]Handler
+	running  map[int]context.CancelFunc
+	wake     chan struct{}
+	workers  sync.WaitGroup
+}
+
+// Default takes JOB_WORKERS (2) and JOB_MAX_ATTEMPTS (3) from the env
+var Default = &Runner{}
+
+// withDefaults fills in unset options - not at init(), the .env file is loaded later in main()
+func (rn *Runner) withDefaults() {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.Workers == 0 {
+		rn.Workers = envInt("JOB_WORKERS", 2)
+	}
+	if rn.MaxAttempts == 0 {
+		rn.MaxAttempts = envInt("JOB_MAX_ATTEMPTS", 3)
+	}
+	if rn.Poll == 0 {
+		rn.Poll = 2 * time.Second
+	}
+	if rn.Heartbeat == 0 {
+		rn.Heartbeat = 5 * time.Second
+	}
+	if rn.StaleAfter == 0 {
+		rn.StaleAfter = time.Minute
+	}
+}
+
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+func Register(jobType string, h Handler) { Default.Register(jobType, h) }
+func Enqueue(jobType string, payload any) (models.Job, error) {
+	return Default.Enqueue(jobType, payload)
+}
+func EnqueueWithAttempts(jobType string, payload any, maxAttempts int) (models.Job, error) {
+	return Default.EnqueueWithAttempts(jobType, payload, maxAttempts)
+}
+func Cancel(id int) (models.Job, error)     { return Default.Cancel(id) }
+func Start(ctx context.Context, db *sql.DB) { Default.Start(ctx, db) }
+func Wait()                                 { Default.Wait() }
+
+func (rn *Runner) Register(jobType string, h Handler) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.handlers == nil {
+		rn.handlers = map[string]Handler{}
+	}
+	rn.handlers[jobType] = h
+}
+
+func (rn *Runner) handler(jobType string) (Handler, bool) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	h, ok := rn.handlers[jobType]
+	return h, ok
+}
+
+// Enqueue stores the job and nudges an idle worker
+func (rn *Runner) Enqueue(jobType string, payload any) (models.Job, error) {
+	return rn.EnqueueWithAttempts(jobType, payload, 0)
+}
+
+// EnqueueWithAttempts is Enqueue with a retry budget of its own (0 = the runner's MaxAttempts)
+func (rn *Runner) EnqueueWithAttempts(jobType string, payload any, maxAttempts int) (models.Job, error) {
+	if _, ok := rn.handler(jobType); !ok {
+		return models.Job{}, fmt.Errorf("unknown job type %q ⚠️", jobType)
+	}
+	rn.withDefaults()
+	if maxAttempts <= 0 {
+		maxAttempts = rn.MaxAttempts
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.Job{}, err
+	}
+	job, err := sqlconnect.AddJobDbHandler(jobType, data, maxAttempts)
+	if err != nil {
+		return models.Job{}, err
+	}
+	rn.mu.Lock()
+	wake := rn.wake
+	rn.mu.Unlock()
+	if wake != nil {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return job, nil
+}
+
+// Cancel stops a queued job right away; a running one is stopped by its worker (here or on another replica)
+func (rn *Runner) Cancel(id int) (models.Job, error) {
+	job, err := sqlconnect.CancelJobDbHandler(id)
+	if err != nil {
+		return job, err
+	}
+	rn.mu.Lock()
+	if cancel, ok := rn.running[id]; ok {
+		cancel()
+	}
+	rn.mu.Unlock()
+	return job, nil
+}
+
+// Start runs the dispatcher and the stale-job sweeper until ctx is done. When it is, running jobs
+// are cancelled and put back into the queue - Wait for that before the process exits.
+func (rn *Runner) Start(ctx context.Context, db *sql.DB) {
+	rn.withDefaults()
+	rn.mu.Lock()
+	rn.db = db
+	rn.wake = make(chan struct{}, 1)
+	rn.running = map[int]context.CancelFunc{}
+	rn.mu.Unlock()
+
+	go rn.sweep(ctx)
+	go rn.dispatch(ctx)
+	log.Printf("job runner started with %d workers", rn.Workers)
+}
+
+func (rn *Runner) dispatch(ctx context.Context) {
+	slots := make(chan struct{}, rn.Workers)
+	for {
+		select {
+		case slots <- struct{}{}: // wait for a free worker
+		case <-ctx.Done():
+			return
+		}
+
+		job, ok, err := sqlconnect.ClaimJobDbHandler(rn.db)
+		if err != nil || !ok {
+			<-slots
+			select {
+			case <-rn.wake:
+			case <-time.After(rn.Poll):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		rn.workers.Add(1)
+		go func() {
+			defer rn.workers.Done()
+			defer func() { <-slots }()
+			rn.execute(ctx, job)
+		}()
+	}
+}
+
+// Wait returns once the jobs that were running at shutdown are back in the queue
+func (rn *Runner) Wait() {
+	rn.workers.Wait()
+}
+
+// sweep puts jobs of crashed workers back into the queue - including our own after a restart
+func (rn *Runner) sweep(ctx context.Context) {
+	ticker := time.NewTicker(rn.StaleAfter / 2)
+	defer ticker.Stop()
+	for {
+		if n, err := sqlconnect.RequeueStaleJobsDbHandler(rn.db, rn.StaleAfter); err == nil && n > 0 {
+			log.Printf("re-queued %d stale jobs", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (rn *Runner) execute(ctx context.Context, job models.Job) {
+	h, ok := rn.handler(job.Type)
+	if !ok {
+		sqlconnect.FinishJobDbHandler(rn.db, job.ID, job.Attempts, models.JobFailed, nil, fmt.Sprintf("unknown job type %q", job.Type))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rn.mu.Lock()
+	rn.running[job.ID] = cancel
+	rn.mu.Unlock()
+	defer func() {
+		rn.mu.Lock()
+		delete(rn.running, job.ID)
+		rn.mu.Unlock()
+	}()
+
+	var done, total atomic.Int64
+	report := func(d, t int) {
+		done.Store(int64(d))
+		total.Store(int64(t))
+	}
+
+	// heartbeat: keeps the claim alive, publishes progress, picks up cancels from other replicas
+	stopHeartbeat := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(rn.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cancelRequested, err := sqlconnect.JobHeartbeatDbHandler(rn.db, job.ID, int(done.Load()), int(total.Load()))
+				if err == nil && cancelRequested {
+					cancel()
+				}
+			case <-stopHeartbeat:
+				return
+			}
+		}
+	}()
+
+	result, err := runSafely(jobCtx, h, job, report)
+	close(stopHeartbeat)
+	<-heartbeatDone
+	sqlconnect.JobHeartbeatDbHandler(rn.db, job.ID, int(done.Load()), int(total.Load()))
+
+	switch {
+	case ctx.Err() != nil:
+		// shutting down - somebody else (or we, after the restart) picks it up again
+		sqlconnect.RetryJobDbHandler(rn.db, job.ID, job.Attempts, "interrupted by shutdown", 0)
+	case jobCtx.Err() != nil:
+		sqlconnect.FinishJobDbHandler(rn.db, job.ID, job.Attempts, models.JobCancelled, nil, "cancelled")
+	case err != nil:
+		if IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+			log.Printf("job %d (%s) failed: %v", job.ID, job.Type, err)
+			sqlconnect.FinishJobDbHandler(rn.db, job.ID, job.Attempts, models.JobFailed, nil, err.Error())
+			return
+		}
+		delay := time.Duration(1<<job.Attempts) * 5 * time.Second // 10s, 20s, 40s, ...
+		log.Printf("job %d (%s) attempt %d failed, retrying in %s: %v", job.ID, job.Type, job.Attempts, delay, err)
+		sqlconnect.RetryJobDbHandler(rn.db, job.ID, job.Attempts, err.Error(), delay)
+	default:
+		data, err := json.Marshal(result)
+		if err != nil {
+			sqlconnect.FinishJobDbHandler(rn.db, job.ID, job.Attempts, models.JobFailed, nil, "invalid job result: "+err.Error())
+			return
+		}
+		sqlconnect.FinishJobDbHandler(rn.db, job.ID, job.Attempts, models.JobSucceeded, data, "")
+	}
+}
+
+// runSafely turns a panicking job into a failed one instead of taking the server down
+func runSafely(ctx context.Context, h Handler, job models.Job, report func(done, total int)) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = Permanent(fmt.Errorf("job panicked: %v", p))
+		}
+	}()
+	return h(ctx, job, report)
+}
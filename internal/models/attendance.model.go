@@ -0,0 +1,38 @@
+package models
+
+// Attendance statuses
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+type Attendance struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Date      string `json:"date,omitempty" db:"date,omitempty"` // YYYY-MM-DD
+	Period    int    `json:"period,omitempty" db:"period,omitempty"`
+	Status    string `json:"status,omitempty" db:"status,omitempty"`
+	MarkedBy  int    `json:"marked_by,omitempty" db:"marked_by,omitempty"` // teacher-ID
+	Note      string `json:"note,omitempty" db:"note,omitempty"`
+}
+
+// AttendanceSheet - bulk body for POST /classes/{id}/attendance
+type AttendanceSheet struct {
+	Date     string       `json:"date"`
+	Period   int          `json:"period"`
+	MarkedBy int          `json:"marked_by"`
+	Records  []Attendance `json:"records"` // student_id, status, note
+}
+
+// AttendanceSummary - per-student status counts over a date range
+type AttendanceSummary struct {
+	StudentID int `json:"student_id"`
+	Present   int `json:"present"`
+	Absent    int `json:"absent"`
+	Late      int `json:"late"`
+	Excused   int `json:"excused"`
+	Total     int `json:"total"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type Class struct {
+	ID                int    `json:"id,omitempty" db:"id,omitempty"`
+	Name              string `json:"name,omitempty" db:"name,omitempty"`
+	Grade             int    `json:"grade,omitempty" db:"grade,omitempty"`
+	Section           string `json:"section,omitempty" db:"section,omitempty"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id,omitempty" db:"homeroom_teacher_id,omitempty"` // 0 => no homeroom teacher (NULL)
+	Capacity          int    `json:"capacity,omitempty" db:"capacity,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+type AcademicYear struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Name      string `json:"name,omitempty" db:"name,omitempty"`             // "2026/27"
+	StartDate string `json:"start_date,omitempty" db:"start_date,omitempty"` // YYYY-MM-DD
+	EndDate   string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	IsCurrent bool   `json:"is_current" db:"is_current"`
+}
+
+type Term struct {
+	ID             int    `json:"id,omitempty" db:"id,omitempty"`
+	AcademicYearID int    `json:"academic_year_id,omitempty" db:"academic_year_id,omitempty"`
+	Name           string `json:"name,omitempty" db:"name,omitempty"` // "T1"
+	StartDate      string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate        string `json:"end_date,omitempty" db:"end_date,omitempty"`
+}
+
+// Enrollment reasons
+const (
+	EnrollmentEnrolled  = "enrolled"
+	EnrollmentTransfer  = "transfer"
+	EnrollmentPromotion = "promotion"
+)
+
+type Enrollment struct {
+	ID             int    `json:"id,omitempty" db:"id,omitempty"`
+	StudentID      int    `json:"student_id,omitempty" db:"student_id,omitempty"`
+	ClassID        int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	AcademicYearID int    `json:"academic_year_id,omitempty" db:"academic_year_id,omitempty"`
+	StartDate      string `json:"start_date,omitempty" db:"start_date,omitempty"`
+	EndDate        string `json:"end_date,omitempty" db:"end_date,omitempty"` // empty => current
+	Reason         string `json:"reason,omitempty" db:"reason,omitempty"`
+	Class          string `json:"class,omitempty"` // read-only, joined from classes
+}
+
+// TransferRequest - body of POST /students/{id}/transfer
+type TransferRequest struct {
+	ClassID        int    `json:"class_id"`
+	Date           string `json:"date"` // defaults to today
+	AcademicYearID int    `json:"academic_year_id,omitempty"`
+}
+
+// PromotionRequest - body of POST /classes/{id}/promote
+type PromotionRequest struct {
+	ToClassID      int    `json:"to_class_id,omitempty"` // defaults to same section, grade + 1
+	Date           string `json:"date"`
+	AcademicYearID int    `json:"academic_year_id,omitempty"`
+}
+
+// PromotionResult - who moved where
+type PromotionResult struct {
+	FromClassID int   `json:"from_class_id"`
+	ToClassID   int   `json:"to_class_id"`
+	StudentIDs  []int `json:"student_ids"`
+}
+
+// StudentMoved - data of the student.transferred / student.promoted events
+type StudentMoved struct {
+	Student   Student `json:"student"`
+	FromClass string  `json:"from_class"`
+	ToClass   string  `json:"to_class"`
+	Date      string  `json:"date"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// Assessment types
+const (
+	AssessmentExam       = "exam"
+	AssessmentQuiz       = "quiz"
+	AssessmentAssignment = "assignment"
+)
+
+type Assessment struct {
+	ID        int     `json:"id,omitempty" db:"id,omitempty"`
+	Title     string  `json:"title,omitempty" db:"title,omitempty"`
+	Type      string  `json:"type,omitempty" db:"type,omitempty"`
+	SubjectID int     `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	ClassID   int     `json:"class_id,omitempty" db:"class_id,omitempty"`
+	TeacherID int     `json:"teacher_id,omitempty" db:"teacher_id,omitempty"` // 0 => NULL
+	MaxScore  float64 `json:"max_score,omitempty" db:"max_score,omitempty"`
+	Date      string  `json:"date,omitempty" db:"date,omitempty"` // YYYY-MM-DD
+	Term      string  `json:"term,omitempty" db:"term,omitempty"`
+}
+
+type Score struct {
+	ID           int     `json:"id,omitempty" db:"id,omitempty"`
+	AssessmentID int     `json:"assessment_id,omitempty" db:"assessment_id,omitempty"`
+	StudentID    int     `json:"student_id,omitempty" db:"student_id,omitempty"`
+	Score        float64 `json:"score" db:"score"`
+	Remarks      string  `json:"remarks,omitempty" db:"remarks,omitempty"`
+}
+
+// ReportCardLine - a student's average for one subject in one term
+type ReportCardLine struct {
+	Subject     string  `json:"subject"`
+	Term        string  `json:"term"`
+	Assessments int     `json:"assessments"`
+	Average     float64 `json:"average"` // percentage of max_score, 0-100
+	Grade       string  `json:"grade"`
+}
+
+// GradeBucket - one bar of a class' grade distribution
+type GradeBucket struct {
+	Grade string `json:"grade"`
+	Count int    `json:"count"`
+}
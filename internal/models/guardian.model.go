@@ -0,0 +1,21 @@
+package models
+
+const (
+	RelationMother      = "mother"
+	RelationFather      = "father"
+	RelationGrandparent = "grandparent"
+	RelationSibling     = "sibling"
+	RelationGuardian    = "guardian"
+	RelationOther       = "other"
+)
+
+type Guardian struct {
+	ID         int    `json:"id,omitempty" db:"id,omitempty"`
+	FirstName  string `json:"first_name,omitempty" db:"first_name,omitempty"`
+	LastName   string `json:"last_name,omitempty" db:"last_name,omitempty"`
+	Relation   string `json:"relation,omitempty" db:"relation,omitempty"`
+	Phone      string `json:"phone,omitempty" db:"phone,omitempty"`
+	Email      string `json:"email,omitempty" db:"email,omitempty"`
+	IsPrimary  bool   `json:"is_primary" db:"is_primary"`
+	StudentIDs []int  `json:"student_ids,omitempty"` // kept in student_guardians
+}
@@ -0,0 +1,32 @@
+package models
+
+// import modes - ?mode=atomic (default) or ?mode=best-effort
+const (
+	ImportAtomic     = "atomic"
+	ImportBestEffort = "best-effort"
+)
+
+// per-row import statuses
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+	ImportSkipped = "skipped" // valid, but not written because the atomic import was rolled back
+)
+
+type ImportRowResult struct {
+	Row    int    `json:"row"` // line in the CSV file, the header is line 1
+	Status string `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "encoding/json"
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+type Job struct {
+	ID          int               `json:"id"`
+	Type        string            `json:"type"`
+	Status      string            `json:"status"`
+	Payload     json.RawMessage   `json:"-"`
+	Result      json.RawMessage   `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Done        int               `json:"done"`
+	Total       int               `json:"total,omitempty"`    // 0 => unknown up front (exports)
+	Progress    *int              `json:"progress,omitempty"` // percent, only when total is known
+	Attempts    int               `json:"attempts"`
+	MaxAttempts int               `json:"max_attempts"`
+	CreatedAt   string            `json:"created_at"`
+	StartedAt   string            `json:"started_at,omitempty"`
+	FinishedAt  string            `json:"finished_at,omitempty"`
+	Links       map[string]string `json:"links,omitempty"`
+}
+
+func (j Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
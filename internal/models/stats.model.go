@@ -0,0 +1,7 @@
+package models
+
+// GroupCount - one row of a GROUP BY ... COUNT(*) report
+type GroupCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
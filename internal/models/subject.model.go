@@ -0,0 +1,17 @@
+package models
+
+type Subject struct {
+	ID   int    `json:"id,omitempty" db:"id,omitempty"`
+	Name string `json:"name,omitempty" db:"name,omitempty"`
+	Code string `json:"code,omitempty" db:"code,omitempty"`
+}
+
+// Assignment - a teacher teaching a subject to a class (teacher_subject_class)
+type Assignment struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	SubjectID int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	Subject   string `json:"subject,omitempty"` // read-only, joined from subjects
+	Class     string `json:"class,omitempty"`   // read-only, joined from classes
+}
@@ -7,4 +7,7 @@ type Teacher struct {
 	Email     string `json:"email,omitempty"  db:"email,omitempty"`
 	Class     string `json:"class,omitempty"  db:"class,omitempty"`
 	Subject   string `json:"subject,omitempty"  db:"subject,omitempty"`
+
+	// computed fields - no db-tag, only filled in when requested via ?include=
+	StudentCount *int `json:"student_count,omitempty"`
 }
@@ -0,0 +1,25 @@
+package models
+
+type TimetableSlot struct {
+	ID        int    `json:"id,omitempty" db:"id,omitempty"`
+	Day       int    `json:"day,omitempty" db:"day,omitempty"` // 1 = Monday ... 7 = Sunday
+	Period    int    `json:"period,omitempty" db:"period,omitempty"`
+	ClassID   int    `json:"class_id,omitempty" db:"class_id,omitempty"`
+	SubjectID int    `json:"subject_id,omitempty" db:"subject_id,omitempty"`
+	TeacherID int    `json:"teacher_id,omitempty" db:"teacher_id,omitempty"`
+	Room      string `json:"room,omitempty" db:"room,omitempty"`
+
+	// read-only, joined in for the timetable views
+	Class     string `json:"class,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	Teacher   string `json:"teacher,omitempty"`
+	StartTime string `json:"start_time,omitempty"` // HH:MM:SS
+	EndTime   string `json:"end_time,omitempty"`
+}
+
+// TimetableClash - why a slot can't be booked, and the slot it collides with
+type TimetableClash struct {
+	Reason  string        `json:"reason"` // teacher | class | room
+	Message string        `json:"message"`
+	Slot    TimetableSlot `json:"conflicting_slot"`
+}
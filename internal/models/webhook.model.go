@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+)
+
+// Delivery statuses
+const (
+	DeliveryWaiting   = "waiting" // behind an earlier delivery of the same aggregate to the same webhook
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID          int      `json:"id,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Events      []string `json:"events,omitempty"` // "teacher.created", "student.*", "*"
+	Secret      string   `json:"secret,omitempty"` // only shown when the webhook is created
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"` // defaults to true
+	CreatedAt   string   `json:"created_at,omitempty"`
+}
+
+// Wants reports whether the webhook subscribed to the event type
+func (w Webhook) Wants(eventType string) bool {
+	return events.Match(w.Events, eventType)
+}
+
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	AggregateType  string          `json:"aggregate_type,omitempty"`
+	AggregateID    int             `json:"aggregate_id,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	DurationMS     int             `json:"duration_ms,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	LastAttemptAt  string          `json:"last_attempt_at,omitempty"`
+}
+
+// WebhookDeliveryJob - the payload of the job that sends a delivery
+type WebhookDeliveryJob struct {
+	DeliveryID int `json:"delivery_id"`
+	WebhookID  int `json:"webhook_id"`
+}
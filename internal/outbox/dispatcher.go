@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// Relays the events the DB handlers wrote to the outbox table on to the sinks.
+// Delivery is at-least-once: an event is marked dispatched only after every sink took it,
+// a failing sink gets the event again later (and so do the sinks that already had it).
+
+type Dispatcher struct {
+	Sinks     []events.Sink
+	Batch     int           // events per round
+	Poll      time.Duration // pause when the outbox is empty
+	MaxDelay  time.Duration // cap of the retry backoff
+	Retention time.Duration // dispatched events are purged after this long
+
+	db        *sql.DB // long-lived - the dispatcher polls every Poll
+	lastPurge time.Time
+}
+
+var Default = &Dispatcher{}
+
+func (d *Dispatcher) withDefaults() {
+	if d.Batch == 0 {
+		d.Batch = 100
+	}
+	if d.Poll == 0 {
+		d.Poll = time.Second
+	}
+	if d.MaxDelay == 0 {
+		d.MaxDelay = 10 * time.Minute
+	}
+	if d.Retention == 0 {
+		d.Retention = 7 * 24 * time.Hour
+	}
+}
+
+// Start dispatches to the given sinks until ctx is done
+func Start(ctx context.Context, db *sql.DB, sinks ...events.Sink) {
+	Default.Sinks = append(Default.Sinks, sinks...)
+	Default.Start(ctx, db)
+}
+
+func (d *Dispatcher) Start(ctx context.Context, db *sql.DB) {
+	d.withDefaults()
+	d.db = db
+	go d.run(ctx)
+	log.Printf("outbox dispatcher started with %d sinks", len(d.Sinks))
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	for {
+		n, err := sqlconnect.DispatchOutboxDbHandler(d.db, d.Batch, func(e events.Event) error { return d.send(ctx, e) }, d.backoff)
+		if err != nil {
+			log.Printf("outbox: %v", err)
+		}
+		d.purge()
+
+		// a full batch means there's probably more waiting
+		if err == nil && n == d.Batch {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-time.After(d.Poll):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, e events.Event) error {
+	var errs []error
+	for _, sink := range d.Sinks {
+		if err := sink.Send(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		log.Printf("outbox: event %s (%s) not delivered: %v", e.ID, e.Type, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// backoff - 2s, 4s, 8s, ... up to MaxDelay
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	if attempts > 20 {
+		return d.MaxDelay
+	}
+	return min(time.Duration(1<<attempts)*time.Second, d.MaxDelay)
+}
+
+func (d *Dispatcher) purge() {
+	if time.Since(d.lastPurge) < time.Hour {
+		return
+	}
+	d.lastPurge = time.Now()
+	if n, err := sqlconnect.PurgeOutboxDbHandler(d.db, d.Retention); err == nil && n > 0 {
+		log.Printf("outbox: purged %d dispatched events", n)
+	}
+}
@@ -0,0 +1,222 @@
+package sqlconnect
+
+import (
+	"database/sql"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const academicYearColumns = "id, name, start_date, end_date, is_current"
+
+func queryAcademicYears(db queryer, qry string, args ...any) ([]models.AcademicYear, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving academic years! ⚠️")
+	}
+	defer rows.Close()
+
+	years := []models.AcademicYear{}
+	for rows.Next() {
+		var y models.AcademicYear
+		if err := rows.Scan(&y.ID, &y.Name, &y.StartDate, &y.EndDate, &y.IsCurrent); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		years = append(years, y)
+	}
+	return years, nil
+}
+
+//! GET All academic years DB ops.
+func GetAcademicYearsDbHandler() ([]models.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	return queryAcademicYears(db, "SELECT "+academicYearColumns+" FROM academic_years ORDER BY start_date DESC")
+}
+
+//! GET single academic year DB ops.
+func GetAcademicYearDbHandler(id int) (models.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	years, err := queryAcademicYears(db, "SELECT "+academicYearColumns+" FROM academic_years WHERE id = ?", id)
+	if err != nil {
+		return models.AcademicYear{}, err
+	}
+	if len(years) == 0 {
+		return models.AcademicYear{}, utils.ErrorHandler(sql.ErrNoRows, "Academic year Not Found! ⚠️")
+	}
+	return years[0], nil
+}
+
+// saveAcademicYear inserts (ID == 0) or updates the year; only one year can be current
+func saveAcademicYear(db *sql.DB, y models.AcademicYear) (models.AcademicYear, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	if y.IsCurrent {
+		if _, err := tx.Exec("UPDATE academic_years SET is_current = FALSE WHERE id <> ?", y.ID); err != nil {
+			tx.Rollback()
+			return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR updating academic years ⚠️")
+		}
+	}
+
+	if y.ID == 0 {
+		res, err := tx.Exec("INSERT INTO academic_years (name, start_date, end_date, is_current) VALUES (?, ?, ?, ?)",
+			y.Name, y.StartDate, y.EndDate, y.IsCurrent)
+		if err != nil {
+			tx.Rollback()
+			return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR inserting academic year ⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		y.ID = int(lastId)
+	} else {
+		_, err := tx.Exec("UPDATE academic_years SET name = ?, start_date = ?, end_date = ?, is_current = ? WHERE id = ?",
+			y.Name, y.StartDate, y.EndDate, y.IsCurrent, y.ID)
+		if err != nil {
+			tx.Rollback()
+			return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR updating academic year ⚠️")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return y, nil
+}
+
+//! Add / POST academic year DB ops.
+func AddAcademicYearDbHandler(y models.AcademicYear) (models.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	y.ID = 0
+	return saveAcademicYear(db, y)
+}
+
+//! PATCH academic year DB ops.
+func PatchAcademicYearDbHandler(id int, updates map[string]any) (models.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	years, err := queryAcademicYears(db, "SELECT "+academicYearColumns+" FROM academic_years WHERE id = ?", id)
+	if err != nil {
+		return models.AcademicYear{}, err
+	}
+	if len(years) == 0 {
+		return models.AcademicYear{}, utils.ErrorHandler(sql.ErrNoRows, "Academic year Not Found ⚠️")
+	}
+
+	year := years[0]
+	if err := ApplyUpdates(&year, updates); err != nil {
+		return models.AcademicYear{}, utils.ErrorHandler(err, "ERROR: Invalid value in update! ⚠️")
+	}
+	return saveAcademicYear(db, year)
+}
+
+//! Delete academic year (and its terms) DB ops.
+func DeleteAcademicYearDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM academic_years WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting academic year ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting academic year ⚠️")
+	}
+	if rowsAffected == 0 {
+		return utils.ErrorHandler(err, "Academic year Not Found ⚠️")
+	}
+	return nil
+}
+
+//! GET terms of an academic year DB ops.
+func GetTermsDbHandler(yearId int) ([]models.Term, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "academic_years", yearId); err != nil {
+		return nil, utils.ErrorHandler(err, "Academic year Not Found! ⚠️")
+	}
+
+	rows, err := db.Query("SELECT id, academic_year_id, name, start_date, end_date FROM terms WHERE academic_year_id = ? ORDER BY start_date", yearId)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving terms! ⚠️")
+	}
+	defer rows.Close()
+
+	terms := []models.Term{}
+	for rows.Next() {
+		var t models.Term
+		if err := rows.Scan(&t.ID, &t.AcademicYearID, &t.Name, &t.StartDate, &t.EndDate); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+//! Add / POST terms to an academic year DB ops.
+func AddTermsDbHandler(yearId int, newTerms []models.Term) ([]models.Term, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	added := make([]models.Term, len(newTerms))
+	for i, t := range newTerms {
+		res, err := tx.Exec("INSERT INTO terms (academic_year_id, name, start_date, end_date) VALUES (?, ?, ?, ?)",
+			yearId, t.Name, t.StartDate, t.EndDate)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR inserting term - unknown academic year or duplicate name? ⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		t.ID = int(lastId)
+		t.AcademicYearID = yearId
+		added[i] = t
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return added, nil
+}
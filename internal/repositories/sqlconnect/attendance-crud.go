@@ -0,0 +1,296 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const attendanceColumns = "id, student_id, class_id, date, period, status, marked_by, note"
+
+// query-param -> db-column
+var attendanceFilterParams = map[string]string{
+	"date":   "date",
+	"period": "period",
+	"status": "status",
+}
+
+func IsValidAttendanceStatus(status string) bool {
+	switch status {
+	case models.AttendancePresent, models.AttendanceAbsent, models.AttendanceLate, models.AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+// AddDateRange adds ?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive, both optional) on column
+func AddDateRange(r *http.Request, qry string, args []any, column string) (string, []any, error) {
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		val := r.URL.Query().Get(param)
+		if val == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, val); err != nil {
+			return qry, args, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD ⚠️", param, val)
+		}
+		qry += " AND " + column + " " + op + " ?"
+		args = append(args, val)
+	}
+	return qry, args, nil
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryAttendance(db queryer, qry string, args ...any) ([]models.Attendance, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving attendance! ⚠️")
+	}
+	defer rows.Close()
+
+	records := []models.Attendance{}
+	for rows.Next() {
+		var a models.Attendance
+		var markedBy sql.NullInt64
+		if err := rows.Scan(&a.ID, &a.StudentID, &a.ClassID, &a.Date, &a.Period, &a.Status, &markedBy, &a.Note); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		a.MarkedBy = int(markedBy.Int64)
+		records = append(records, a)
+	}
+	return records, nil
+}
+
+func teachesClass(db *sql.DB, teacherId int, className string) error {
+	var found int
+	err := db.QueryRow("SELECT 1 FROM "+teacherClassesSQL+" tc WHERE tc.teacher_id = ? AND tc.class_name = ? LIMIT 1",
+		teacherId, className).Scan(&found)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("teacher %d does not teach class %s ⚠️", teacherId, className)
+	} else if err != nil {
+		return utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return nil
+}
+
+//! CHECK a teacher teaches a class DB ops. (own class or a subject assignment)
+func TeachesClassDbHandler(teacherId int, className string) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	return teachesClass(db, teacherId, className)
+}
+
+//! Bulk-mark attendance for a class DB ops. (re-marking a student overwrites the earlier mark)
+func MarkClassAttendanceDbHandler(classId int, sheet models.AttendanceSheet) ([]models.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	var className string
+	err = db.QueryRow("SELECT name FROM classes WHERE id = ?", classId).Scan(&className)
+	if err == sql.ErrNoRows {
+		return nil, utils.ErrorHandler(err, "Class Not Found! ⚠️")
+	} else if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	// only a teacher of the class may mark it
+	if err := teachesClass(db, sheet.MarkedBy, className); err != nil {
+		return nil, err
+	}
+
+	// ...and only for students of that class
+	rows, err := db.Query("SELECT id FROM students WHERE class = ?", className)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	inClass := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		inClass[id] = true
+	}
+	rows.Close()
+	for _, record := range sheet.Records {
+		if !inClass[record.StudentID] {
+			return nil, fmt.Errorf("student %d is not in class %s ⚠️", record.StudentID, className)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO attendance (student_id, class_id, date, period, status, marked_by, note)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE class_id = VALUES(class_id), status = VALUES(status),
+		marked_by = VALUES(marked_by), note = VALUES(note)`)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	for _, record := range sheet.Records {
+		_, err := stmt.Exec(record.StudentID, classId, sheet.Date, sheet.Period, record.Status, nullableInt(sheet.MarkedBy), record.Note)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR marking attendance ⚠️")
+		}
+	}
+
+	// read the sheet back from inside the tx, so the IDs are there as well
+	marked, err := queryAttendance(tx, "SELECT "+attendanceColumns+" FROM attendance WHERE class_id = ? AND date = ? AND period = ? ORDER BY student_id",
+		classId, sheet.Date, sheet.Period)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return marked, nil
+}
+
+//! GET attendance of a class DB ops. - ?date=&period=&status=&from=&to=
+func GetClassAttendanceDbHandler(classId int, r *http.Request) ([]models.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, classId); err != nil {
+		return nil, err
+	}
+
+	qry := "SELECT " + attendanceColumns + " FROM attendance WHERE class_id = ?"
+	args := []any{classId}
+	qry, args = AddFiltersFor(r, qry, args, attendanceFilterParams)
+	qry, args, err = AddDateRange(r, qry, args, "date")
+	if err != nil {
+		return nil, err
+	}
+	qry += " ORDER BY date DESC, period, student_id"
+	qry, args = AddPagination(r, qry, args)
+
+	return queryAttendance(db, qry, args...)
+}
+
+//! GET attendance history of a student DB ops. - ?from=&to=&status=
+func GetStudentAttendanceDbHandler(studentId int, r *http.Request) ([]models.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err != nil {
+		return nil, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	}
+
+	qry := "SELECT " + attendanceColumns + " FROM attendance WHERE student_id = ?"
+	args := []any{studentId}
+	qry, args = AddFiltersFor(r, qry, args, attendanceFilterParams)
+	qry, args, err = AddDateRange(r, qry, args, "date")
+	if err != nil {
+		return nil, err
+	}
+	qry += " ORDER BY date DESC, period"
+	qry, args = AddPagination(r, qry, args)
+
+	return queryAttendance(db, qry, args...)
+}
+
+const attendanceSummaryColumns = `student_id,
+	SUM(status = 'present'), SUM(status = 'absent'), SUM(status = 'late'), SUM(status = 'excused'), COUNT(*)`
+
+func querySummaries(db *sql.DB, qry string, args ...any) ([]models.AttendanceSummary, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. summarizing attendance! ⚠️")
+	}
+	defer rows.Close()
+
+	summaries := []models.AttendanceSummary{}
+	for rows.Next() {
+		var s models.AttendanceSummary
+		if err := rows.Scan(&s.StudentID, &s.Present, &s.Absent, &s.Late, &s.Excused, &s.Total); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+//! GET absence summary of a student DB ops. - ?from=&to=
+func GetStudentAttendanceSummaryDbHandler(studentId int, r *http.Request) (models.AttendanceSummary, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.AttendanceSummary{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err != nil {
+		return models.AttendanceSummary{}, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	}
+
+	qry := "SELECT " + attendanceSummaryColumns + " FROM attendance WHERE student_id = ?"
+	args := []any{studentId}
+	qry, args, err = AddDateRange(r, qry, args, "date")
+	if err != nil {
+		return models.AttendanceSummary{}, err
+	}
+	qry += " GROUP BY student_id"
+
+	summaries, err := querySummaries(db, qry, args...)
+	if err != nil {
+		return models.AttendanceSummary{}, err
+	}
+	if len(summaries) == 0 {
+		// nothing marked yet in that range
+		return models.AttendanceSummary{StudentID: studentId}, nil
+	}
+	return summaries[0], nil
+}
+
+//! GET absence summary of a whole class DB ops. - ?from=&to=, most absent students first
+func GetClassAttendanceSummaryDbHandler(classId int, r *http.Request) ([]models.AttendanceSummary, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, classId); err != nil {
+		return nil, err
+	}
+
+	qry := "SELECT " + attendanceSummaryColumns + " FROM attendance WHERE class_id = ?"
+	args := []any{classId}
+	qry, args, err = AddDateRange(r, qry, args, "date")
+	if err != nil {
+		return nil, err
+	}
+	qry += " GROUP BY student_id ORDER BY SUM(status = 'absent') DESC, student_id"
+
+	return querySummaries(db, qry, args...)
+}
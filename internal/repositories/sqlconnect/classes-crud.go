@@ -0,0 +1,411 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// query-param -> db-column
+var classFilterParams = map[string]string{
+	"name":                "name",
+	"grade":               "grade",
+	"section":             "section",
+	"homeroom_teacher_id": "homeroom_teacher_id",
+}
+
+// sortby-field -> db-column
+var classSortFields = map[string]string{
+	"name":     "name",
+	"grade":    "grade",
+	"section":  "section",
+	"capacity": "capacity",
+}
+
+const classColumns = "id, name, grade, section, homeroom_teacher_id, capacity"
+
+// homeroom_teacher_id is nullable, 0 in the model means "no homeroom teacher"
+func nullableInt(v int) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// ErrClassNotFound - reported as 404
+var ErrClassNotFound = errors.New("Class Not Found ⚠️")
+
+// ErrClassNameTaken - another class already has the name, reported as 409
+var ErrClassNameTaken = errors.New("a class with that name already exists ⚠️")
+
+// ClassInUseError - teachers/students are still assigned to the class, or its enrollments, attendance,
+// assessments, subject assignments or timetable slots still exist - reported as 409
+type ClassInUseError struct {
+	Members int
+	Records int
+}
+
+func (e *ClassInUseError) Error() string {
+	if e.Members == 0 && e.Records == 0 {
+		return "class is still in use ⚠️" // only the foreign key told us
+	}
+	return fmt.Sprintf("class is still assigned to %d teacher(s)/student(s) and has %d enrollment/attendance/assessment/assignment/timetable record(s) ⚠️", e.Members, e.Records)
+}
+
+// classWriteError maps the constraint errors of an INSERT/UPDATE on classes
+func classWriteError(err error, class models.Class, msg string) error {
+	switch {
+	case isMySQLError(err, errDuplicateEntry):
+		return ErrClassNameTaken
+	case isMySQLError(err, errNoReferencedRow):
+		return &ValidationError{fmt.Errorf("homeroom teacher %d doesn't exist ⚠️", class.HomeroomTeacherID)}
+	}
+	return utils.ErrorHandler(err, msg)
+}
+
+func scanClass(row interface{ Scan(...any) error }) (models.Class, error) {
+	var c models.Class
+	var homeroom sql.NullInt64
+	err := row.Scan(&c.ID, &c.Name, &c.Grade, &c.Section, &homeroom, &c.Capacity)
+	c.HomeroomTeacherID = int(homeroom.Int64)
+	return c, err
+}
+
+//! Validate class-names against the classes table - every teacher/student needs one
+func ValidateClasses(names ...string) error {
+	var distinct []string
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("class is required ⚠️")
+		}
+		if !slices.Contains(distinct, name) {
+			distinct = append(distinct, name)
+		}
+	}
+	if len(distinct) == 0 {
+		return nil
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	args := make([]any, len(distinct))
+	for i, name := range distinct {
+		args[i] = name
+	}
+	rows, err := db.Query("SELECT name FROM classes WHERE name IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR validating class ⚠️")
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return utils.ErrorHandler(err, "ERROR validating class ⚠️")
+		}
+		found[name] = true
+	}
+	for _, name := range distinct {
+		if !found[name] {
+			return fmt.Errorf("unknown class %q ⚠️", name)
+		}
+	}
+	return nil
+}
+
+//! GET All classes DB ops.
+func GetClassesDbHandler(r *http.Request) ([]models.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT " + classColumns + " FROM classes WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, classFilterParams)
+	qry = AddSortingFor(r, qry, classSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	classes := []models.Class{}
+	for rows.Next() {
+		c, err := scanClass(rows)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		classes = append(classes, c)
+	}
+	return classes, nil
+}
+
+//! GET single class by ID DB ops.
+func GetClassDbHandler(id int) (models.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	class, err := scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Class{}, utils.ErrorHandler(err, "Class Not Found! ⚠️")
+	} else if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return class, nil
+}
+
+//! Add / POST classes DB ops.
+func AddClassesDbHandler(newClasses []models.Class) ([]models.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO classes (name, grade, section, homeroom_teacher_id, capacity) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	addedClasses := make([]models.Class, len(newClasses))
+	for i, c := range newClasses {
+		res, err := stmt.Exec(c.Name, c.Grade, c.Section, nullableInt(c.HomeroomTeacherID), c.Capacity)
+		if err != nil {
+			tx.Rollback()
+			return nil, classWriteError(err, c, "ERROR inserting DATA into DB⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		c.ID = int(lastId)
+		addedClasses[i] = c
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return addedClasses, nil
+}
+
+// saveClass writes the class and, if it was renamed, carries the new name over
+// to the teachers/students referencing it - all inside one transaction.
+func saveClass(db *sql.DB, oldName string, class models.Class) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	_, err = tx.Exec("UPDATE classes SET name = ?, grade = ?, section = ?, homeroom_teacher_id = ?, capacity = ? WHERE id = ?",
+		class.Name, class.Grade, class.Section, nullableInt(class.HomeroomTeacherID), class.Capacity, class.ID)
+	if err != nil {
+		tx.Rollback()
+		return classWriteError(err, class, "ERROR updating class ⚠️")
+	}
+
+	if oldName != class.Name {
+		for _, table := range []string{"teachers", "students"} {
+			_, err = tx.Exec("UPDATE "+table+" SET class = ? WHERE class = ?", class.Name, oldName)
+			if err != nil {
+				tx.Rollback()
+				return utils.ErrorHandler(err, "ERROR renaming class ⚠️")
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return nil
+}
+
+//! Update/PUT class Db ops.
+func UpdateClassDbHandler(id int, updatedClass models.Class) (models.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	existingClass, err := scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Class{}, ErrClassNotFound
+	} else if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "ERROR: Unable to retrieve data ⚠️")
+	}
+	updatedClass.ID = existingClass.ID
+
+	if err := saveClass(db, existingClass.Name, updatedClass); err != nil {
+		return models.Class{}, err
+	}
+	return updatedClass, nil
+}
+
+//! PATCH single class Db ops. - validate checks the merged class before it's saved
+func PatchClassDbHandler(id int, updates map[string]any, validate func(models.Class) error) (models.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	existingClass, err := scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Class{}, ErrClassNotFound
+	} else if err != nil {
+		return models.Class{}, utils.ErrorHandler(err, "ERROR: Unable to retrieve data ⚠️")
+	}
+
+	oldName := existingClass.Name
+	if err := ApplyUpdates(&existingClass, updates); err != nil {
+		return models.Class{}, &ValidationError{Err: err}
+	}
+	if err := validate(existingClass); err != nil {
+		return models.Class{}, &ValidationError{Err: err}
+	}
+
+	if err := saveClass(db, oldName, existingClass); err != nil {
+		return models.Class{}, err
+	}
+	return existingClass, nil
+}
+
+//! Delete single class Db ops.
+func DeleteClassDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	// a class can only go once nobody references it anymore - that includes its history and
+	// its timetable, which the foreign key would otherwise silently delete along with it
+	var members, records int
+	err = db.QueryRow(`
+	SELECT (SELECT COUNT(*) FROM teachers t WHERE t.class = c.name) +
+	       (SELECT COUNT(*) FROM students s WHERE s.class = c.name),
+	       (SELECT COUNT(*) FROM enrollments e WHERE e.class_id = c.id) +
+	       (SELECT COUNT(*) FROM attendance a WHERE a.class_id = c.id) +
+	       (SELECT COUNT(*) FROM assessments x WHERE x.class_id = c.id) +
+	       (SELECT COUNT(*) FROM teacher_subject_class tsc WHERE tsc.class_id = c.id) +
+	       (SELECT COUNT(*) FROM timetable_slots ts WHERE ts.class_id = c.id)
+	FROM classes c WHERE c.id = ?`, id).Scan(&members, &records)
+	if err == sql.ErrNoRows {
+		return ErrClassNotFound
+	} else if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting class ⚠️")
+	}
+	if members > 0 || records > 0 {
+		return &ClassInUseError{Members: members, Records: records}
+	}
+
+	_, err = db.Exec("DELETE FROM classes WHERE id = ?", id)
+	if isMySQLError(err, errRowIsReferenced) {
+		return &ClassInUseError{} // referenced in between
+	} else if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting class ⚠️")
+	}
+	return nil
+}
+
+//! GET students of a class DB ops.
+func GetClassStudentsDbHandler(id int) ([]models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+	SELECT s.id, s.first_name, s.last_name, s.email, s.class
+	FROM students s JOIN classes c ON c.name = s.class
+	WHERE c.id = ? ORDER BY s.last_name, s.first_name`, id)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	students := []models.Student{}
+	for rows.Next() {
+		var s models.Student
+		if err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		students = append(students, s)
+	}
+	return students, nil
+}
+
+//! GET teachers of a class DB ops.
+func GetClassTeachersDbHandler(id int) ([]models.Teacher, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+	SELECT DISTINCT t.id, t.first_name, t.last_name, t.email, t.class, t.subject
+	FROM teachers t
+	JOIN `+teacherClassesSQL+` tc ON tc.teacher_id = t.id
+	JOIN classes c ON c.name = tc.class_name
+	WHERE c.id = ? ORDER BY t.last_name, t.first_name`, id)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	teachers := []models.Teacher{}
+	for rows.Next() {
+		var t models.Teacher
+		if err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		teachers = append(teachers, t)
+	}
+	return teachers, nil
+}
+
+func classExists(db *sql.DB, id int) error {
+	var found int
+	err := db.QueryRow("SELECT id FROM classes WHERE id = ?", id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return utils.ErrorHandler(err, "Class Not Found! ⚠️")
+	} else if err != nil {
+		return utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return nil
+}
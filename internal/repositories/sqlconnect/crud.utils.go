@@ -0,0 +1,193 @@
+package sqlconnect
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//! Generic filtering (util fx) - params maps the query-param to its db-column
+// A db-column containing "?" is used as a complete condition, the value is bound to every "?".
+func AddFiltersFor(r *http.Request, qry string, args []any, params map[string]string) (string, []any) {
+	for param, dbField := range params {
+		val := r.URL.Query().Get(param)
+		if val == "" {
+			continue
+		}
+		if n := strings.Count(dbField, "?"); n > 0 {
+			qry += " AND " + dbField
+			for range n {
+				args = append(args, val)
+			}
+			continue
+		}
+		qry += " AND " + dbField + " = ?"
+		args = append(args, val)
+	}
+	return qry, args
+}
+
+//! Generic sorting (util fx) - validFields maps the sortby-field to its db-column
+func AddSortingFor(r *http.Request, qry string, validFields map[string]string) string {
+	var orderBy []string
+	for _, param := range r.URL.Query()["sortby"] {
+		parts := strings.Split(param, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		field, order := parts[0], parts[1]
+		dbField, ok := validFields[field]
+		if !ok || !IsValidSortOrder(order) {
+			continue
+		}
+		orderBy = append(orderBy, dbField+" "+order)
+	}
+
+	// skip the ORDER BY clause entirely if nothing valid was passed
+	if len(orderBy) > 0 {
+		qry += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	return qry
+}
+
+const maxPageLimit = 100
+
+//! Pagination (util fx) - ?page=2&limit=20, no limit => no pagination at all
+func AddPagination(r *http.Request, qry string, args []any) (string, []any) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		return qry, args
+	}
+	limit = min(limit, maxPageLimit)
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	qry += " LIMIT ? OFFSET ?"
+	args = append(args, limit, (page-1)*limit)
+	return qry, args
+}
+
+// withAlias qualifies every db-column with a table alias ("class" -> "s.class") for JOIN queries
+func withAlias(alias string, columns map[string]string) map[string]string {
+	aliased := make(map[string]string, len(columns))
+	for k, v := range columns {
+		aliased[k] = alias + "." + v
+	}
+	return aliased
+}
+
+// dbColumns lists the db-tagged columns of a model (id excluded)
+func dbColumns(model any) []string {
+	modelType := reflect.TypeOf(model)
+	var columns []string
+	for i := 0; i < modelType.NumField(); i++ {
+		column := strings.Split(modelType.Field(i).Tag.Get("db"), ",")[0]
+		if column != "" && column != "id" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// placeholders returns "?, ?, ?" for n args - used for IN (...) clauses
+func placeholders(n int) string {
+	if n < 1 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// MySQL/MariaDB error numbers the handlers tell apart from a broken database
+const (
+	errDuplicateEntry  = 1062 // a UNIQUE key is taken
+	errRowIsReferenced = 1451 // a RESTRICT foreign key still points at the row
+	errNoReferencedRow = 1452 // a foreign key points at nothing
+)
+
+// isMySQLError - check before the error goes through utils.ErrorHandler, which drops it
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
+// ValidationError - the (merged) data of a request is invalid, reported as 400
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+//! Apply a PATCH-map onto a model using REFLECTION (matches on the json-tag)
+func ApplyUpdates(model any, updates map[string]any) error {
+	modelVal := reflect.ValueOf(model).Elem()
+	modelType := modelVal.Type()
+
+	for k, v := range updates {
+		if k == "id" {
+			continue // never update the id field
+		}
+		for i := 0; i < modelVal.NumField(); i++ {
+			field := modelType.Field(i)
+			if strings.Split(field.Tag.Get("json"), ",")[0] != k {
+				continue
+			}
+			// computed/read-only fields have no db-tag and can't be written
+			if field.Tag.Get("db") == "" {
+				break
+			}
+			fieldVal := modelVal.Field(i)
+			if !fieldVal.CanSet() {
+				break
+			}
+			// XML bodies only have text - "12" for an int field is parsed per the field's type
+			if text, ok := v.(string); ok && fieldVal.Kind() != reflect.String {
+				parsed, err := parseText(text, fieldVal.Kind())
+				if err != nil {
+					return fmt.Errorf("cannot use %q for field %s", text, k)
+				}
+				v = parsed
+			}
+			val := reflect.ValueOf(v)
+			if !val.IsValid() || !val.Type().ConvertibleTo(fieldVal.Type()) {
+				return fmt.Errorf("cannot use %v for field %s", v, k)
+			}
+			// JSON numbers arrive as float64, so "abc" -> int would panic but 9.0 -> int is fine
+			if val.Kind() == reflect.String && fieldVal.Kind() != reflect.String {
+				return fmt.Errorf("cannot use %v for field %s", v, k)
+			}
+			fieldVal.Set(val.Convert(fieldVal.Type()))
+			break
+		}
+	}
+	return nil
+}
+
+// parseText converts the text of an update to a bool or number field
+func parseText(text string, kind reflect.Kind) (any, error) {
+	switch kind {
+	case reflect.Bool:
+		return strconv.ParseBool(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(text, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(text, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("%q is not a finite number", text)
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("can't convert %q", text)
+}
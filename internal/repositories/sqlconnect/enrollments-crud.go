@@ -0,0 +1,290 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// Students change class ONLY through enrollments: the open enrollment gets closed,
+// a new one is opened and students.class follows along - always in one transaction.
+
+type execQueryer interface {
+	queryer
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// currentYear resolves 0 to the academic year flagged is_current (NULL if there's none)
+func currentYear(db execQueryer, yearId int) (any, error) {
+	if yearId != 0 {
+		return yearId, nil
+	}
+	var id int
+	err := db.QueryRow("SELECT id FROM academic_years WHERE is_current LIMIT 1").Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return id, err
+}
+
+// openEnrollment enrolls a freshly created student into their class
+func openEnrollment(db execQueryer, studentId int, className string, date string) error {
+	if className == "" {
+		return &ValidationError{fmt.Errorf("class is required ⚠️")}
+	}
+	year, err := currentYear(db, 0)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`INSERT INTO enrollments (student_id, class_id, academic_year_id, start_date, reason)
+	SELECT ?, id, ?, ?, ? FROM classes WHERE name = ?`, studentId, year, date, models.EnrollmentEnrolled, className)
+	if err != nil {
+		return err
+	}
+	// no class of that name - the student would be left without an enrollment
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &ValidationError{fmt.Errorf("unknown class %q ⚠️", className)}
+	}
+	return nil
+}
+
+// moveStudent closes the student's open enrollment on `date` and opens a new one in `toClass`
+func moveStudent(tx *sql.Tx, studentId int, toClass models.Class, date string, year any, reason string) error {
+	var openStart string
+	err := tx.QueryRow("SELECT start_date FROM enrollments WHERE student_id = ? AND end_date IS NULL", studentId).Scan(&openStart)
+	if err != nil && err != sql.ErrNoRows {
+		return utils.ErrorHandler(err, "ERROR retrieving enrollment ⚠️")
+	}
+	if err == nil && date < openStart {
+		return fmt.Errorf("student %d can't leave a class before joining it on %s ⚠️", studentId, openStart)
+	}
+
+	_, err = tx.Exec("UPDATE enrollments SET end_date = ? WHERE student_id = ? AND end_date IS NULL", date, studentId)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR closing enrollment ⚠️")
+	}
+	_, err = tx.Exec("INSERT INTO enrollments (student_id, class_id, academic_year_id, start_date, reason) VALUES (?, ?, ?, ?, ?)",
+		studentId, toClass.ID, year, date, reason)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR opening enrollment ⚠️")
+	}
+	_, err = tx.Exec("UPDATE students SET class = ? WHERE id = ?", toClass.Name, studentId)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR updating student's class ⚠️")
+	}
+	return nil
+}
+
+// checkCapacity refuses to put `incoming` more students into a full class (capacity 0 = unlimited)
+func checkCapacity(tx *sql.Tx, class models.Class, incoming int) error {
+	if class.Capacity == 0 {
+		return nil
+	}
+	var seated int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE class = ?", class.Name).Scan(&seated); err != nil {
+		return utils.ErrorHandler(err, "ERROR checking class capacity ⚠️")
+	}
+	if seated+incoming > class.Capacity {
+		return fmt.Errorf("class %s has %d of %d seats taken, can't add %d more ⚠️", class.Name, seated, class.Capacity, incoming)
+	}
+	return nil
+}
+
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+//! GET enrollment history of a student DB ops.
+func GetStudentEnrollmentsDbHandler(studentId int) ([]models.Enrollment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err != nil {
+		return nil, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	}
+
+	rows, err := db.Query(`
+	SELECT e.id, e.student_id, e.class_id, e.academic_year_id, e.start_date, e.end_date, e.reason, c.name
+	FROM enrollments e JOIN classes c ON c.id = e.class_id
+	WHERE e.student_id = ? ORDER BY e.start_date DESC, e.id DESC`, studentId)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving enrollments! ⚠️")
+	}
+	defer rows.Close()
+
+	enrollments := []models.Enrollment{}
+	for rows.Next() {
+		var e models.Enrollment
+		var year sql.NullInt64
+		var endDate sql.NullString
+		if err := rows.Scan(&e.ID, &e.StudentID, &e.ClassID, &year, &e.StartDate, &endDate, &e.Reason, &e.Class); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		e.AcademicYearID = int(year.Int64)
+		e.EndDate = endDate.String
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, nil
+}
+
+//! TRANSFER a student to another class DB ops.
+func TransferStudentDbHandler(studentId int, req models.TransferRequest) (models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if req.Date == "" {
+		req.Date = today()
+	}
+
+	student, err := getStudent(db, studentId)
+	if err == sql.ErrNoRows {
+		return models.Student{}, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	} else if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	toClass, err := scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", req.ClassID))
+	if err == sql.ErrNoRows {
+		return models.Student{}, utils.ErrorHandler(err, "Class Not Found! ⚠️")
+	} else if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if toClass.Name == student.Class {
+		return models.Student{}, fmt.Errorf("student is already in class %s ⚠️", toClass.Name)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	year, err := currentYear(tx, req.AcademicYearID)
+	if err != nil {
+		tx.Rollback()
+		return models.Student{}, utils.ErrorHandler(err, "ERROR resolving academic year ⚠️")
+	}
+	if err := checkCapacity(tx, toClass, 1); err != nil {
+		tx.Rollback()
+		return models.Student{}, err
+	}
+	if err := moveStudent(tx, studentId, toClass, req.Date, year, models.EnrollmentTransfer); err != nil {
+		tx.Rollback()
+		return models.Student{}, err
+	}
+	fromClass := student.Class
+	student.Class = toClass.Name
+	transferred := models.StudentMoved{Student: student, FromClass: fromClass, ToClass: toClass.Name, Date: req.Date}
+	if err := addOutboxEvent(tx, events.New(events.StudentTransferred, studentId, transferred)); err != nil {
+		tx.Rollback()
+		return models.Student{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return student, nil
+}
+
+//! PROMOTE a whole class DB ops. - every student moves, or nobody does
+func PromoteClassDbHandler(classId int, req models.PromotionRequest) (models.PromotionResult, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if req.Date == "" {
+		req.Date = today()
+	}
+
+	fromClass, err := scanClass(db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", classId))
+	if err == sql.ErrNoRows {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "Class Not Found! ⚠️")
+	} else if err != nil {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	// default target: same section, one grade up (9B -> 10B)
+	var toRow *sql.Row
+	if req.ToClassID != 0 {
+		toRow = db.QueryRow("SELECT "+classColumns+" FROM classes WHERE id = ?", req.ToClassID)
+	} else {
+		toRow = db.QueryRow("SELECT "+classColumns+" FROM classes WHERE grade = ? AND section = ?", fromClass.Grade+1, fromClass.Section)
+	}
+	toClass, err := scanClass(toRow)
+	if err == sql.ErrNoRows {
+		return models.PromotionResult{}, fmt.Errorf("no class to promote %s into ⚠️", fromClass.Name)
+	} else if err != nil {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if toClass.ID == fromClass.ID {
+		return models.PromotionResult{}, fmt.Errorf("can't promote a class into itself ⚠️")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	// lock the roster so nobody joins/leaves the class mid-promotion
+	rows, err := tx.Query("SELECT id FROM students WHERE class = ? ORDER BY id FOR UPDATE", fromClass.Name)
+	if err != nil {
+		tx.Rollback()
+		return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR retrieving students ⚠️")
+	}
+	studentIds := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		studentIds = append(studentIds, id)
+	}
+	rows.Close()
+
+	year, err := currentYear(tx, req.AcademicYearID)
+	if err != nil {
+		tx.Rollback()
+		return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR resolving academic year ⚠️")
+	}
+	if err := checkCapacity(tx, toClass, len(studentIds)); err != nil {
+		tx.Rollback()
+		return models.PromotionResult{}, err
+	}
+	for _, id := range studentIds {
+		if err := moveStudent(tx, id, toClass, req.Date, year, models.EnrollmentPromotion); err != nil {
+			tx.Rollback()
+			return models.PromotionResult{}, err
+		}
+		student, err := getStudent(tx, id)
+		if err != nil {
+			tx.Rollback()
+			return models.PromotionResult{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+		}
+		promoted := models.StudentMoved{Student: student, FromClass: fromClass.Name, ToClass: toClass.Name, Date: req.Date}
+		if err := addOutboxEvent(tx, events.New(events.StudentPromoted, id, promoted)); err != nil {
+			tx.Rollback()
+			return models.PromotionResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PromotionResult{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return models.PromotionResult{FromClassID: fromClass.ID, ToClassID: toClass.ID, StudentIDs: studentIds}, nil
+}
@@ -0,0 +1,69 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// streamRows hands every row to emit straight from rows.Next(), nothing is collected in memory.
+// An error from emit (e.g. the client went away) stops the query.
+func streamRows[T any](qry string, args []any, scan func(*sql.Rows) (T, error), emit func(T) error) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return utils.ErrorHandler(err, "ERROR reading DB-results! ⚠️")
+	}
+	return nil
+}
+
+//! EXPORT teachers DB ops. - same filters/sorting/pagination as GET /teachers
+func ExportTeachersDbHandler(r *http.Request, emit func(models.Teacher) error) error {
+	qry := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
+	var args []any
+	qry, args = AddFilters(r, qry, args)
+	qry = AddSorting(r, qry)
+	qry, args = AddPagination(r, qry, args)
+
+	return streamRows(qry, args, func(rows *sql.Rows) (models.Teacher, error) {
+		var t models.Teacher
+		err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject)
+		return t, err
+	}, emit)
+}
+
+//! EXPORT students DB ops. - same filters/sorting/pagination as GET /students
+func ExportStudentsDbHandler(r *http.Request, emit func(models.Student) error) error {
+	qry := "SELECT id, first_name, last_name, email, class FROM students WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, studentFilterParams)
+	qry = AddSortingFor(r, qry, studentSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	return streamRows(qry, args, func(rows *sql.Rows) (models.Student, error) {
+		var s models.Student
+		err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class)
+		return s, err
+	}, emit)
+}
@@ -0,0 +1,416 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const assessmentColumns = "id, title, type, subject_id, class_id, teacher_id, max_score, date, term"
+
+// query-param -> db-column
+var assessmentFilterParams = map[string]string{
+	"type":       "type",
+	"subject_id": "subject_id",
+	"class_id":   "class_id",
+	"teacher_id": "teacher_id",
+	"term":       "term",
+	"date":       "date",
+}
+
+// sortby-field -> db-column
+var assessmentSortFields = map[string]string{
+	"title":     "title",
+	"date":      "date",
+	"term":      "term",
+	"max_score": "max_score",
+}
+
+// letter grades by minimum percentage, highest first
+var gradeScale = []struct {
+	Grade string
+	Min   float64
+}{{"A", 90}, {"B", 80}, {"C", 70}, {"D", 60}, {"F", 0}}
+
+func LetterGrade(percent float64) string {
+	for _, g := range gradeScale {
+		if percent >= g.Min {
+			return g.Grade
+		}
+	}
+	return gradeScale[len(gradeScale)-1].Grade
+}
+
+// same scale as LetterGrade, as a SQL CASE over a percentage expression
+func gradeCaseSQL(percentExpr string) string {
+	qry := "CASE"
+	for _, g := range gradeScale[:len(gradeScale)-1] {
+		qry += fmt.Sprintf(" WHEN %s >= %g THEN '%s'", percentExpr, g.Min, g.Grade)
+	}
+	return qry + fmt.Sprintf(" ELSE '%s' END", gradeScale[len(gradeScale)-1].Grade)
+}
+
+func IsValidAssessmentType(t string) bool {
+	return t == models.AssessmentExam || t == models.AssessmentQuiz || t == models.AssessmentAssignment
+}
+
+// ErrAssessmentNotFound - reported as 404
+var ErrAssessmentNotFound = errors.New("Assessment Not Found ⚠️")
+
+func scanAssessment(row interface{ Scan(...any) error }) (models.Assessment, error) {
+	var a models.Assessment
+	var teacherId sql.NullInt64
+	err := row.Scan(&a.ID, &a.Title, &a.Type, &a.SubjectID, &a.ClassID, &teacherId, &a.MaxScore, &a.Date, &a.Term)
+	a.TeacherID = int(teacherId.Int64)
+	return a, err
+}
+
+//! GET All assessments DB ops.
+func GetAssessmentsDbHandler(r *http.Request) ([]models.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT " + assessmentColumns + " FROM assessments WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, assessmentFilterParams)
+	qry, args, err = AddDateRange(r, qry, args, "date")
+	if err != nil {
+		return nil, err
+	}
+	qry = AddSortingFor(r, qry, assessmentSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	assessments := []models.Assessment{}
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		assessments = append(assessments, a)
+	}
+	return assessments, nil
+}
+
+//! GET single assessment by ID DB ops.
+func GetAssessmentDbHandler(id int) (models.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	a, err := scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Assessment{}, utils.ErrorHandler(err, "Assessment Not Found! ⚠️")
+	} else if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return a, nil
+}
+
+//! Add / POST assessments DB ops.
+func AddAssessmentsDbHandler(newAssessments []models.Assessment) ([]models.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO assessments (title, type, subject_id, class_id, teacher_id, max_score, date, term)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	added := make([]models.Assessment, len(newAssessments))
+	for i, a := range newAssessments {
+		res, err := stmt.Exec(a.Title, a.Type, a.SubjectID, a.ClassID, nullableInt(a.TeacherID), a.MaxScore, a.Date, a.Term)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR inserting assessment - unknown subject, class or teacher? ⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		a.ID = int(lastId)
+		added[i] = a
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return added, nil
+}
+
+//! PATCH single assessment Db ops. - validate checks the merged assessment before it's saved
+func PatchAssessmentDbHandler(id int, updates map[string]any, validate func(models.Assessment) error) (models.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	a, err := scanAssessment(db.QueryRow("SELECT "+assessmentColumns+" FROM assessments WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Assessment{}, ErrAssessmentNotFound
+	} else if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "Unable to retrieve data ⚠️")
+	}
+
+	if err := ApplyUpdates(&a, updates); err != nil {
+		return models.Assessment{}, &ValidationError{Err: err}
+	}
+	if err := validate(a); err != nil {
+		return models.Assessment{}, &ValidationError{Err: err}
+	}
+
+	// scores already entered have to stay within max_score
+	var highest sql.NullFloat64
+	err = db.QueryRow("SELECT MAX(score) FROM scores WHERE assessment_id = ?", a.ID).Scan(&highest)
+	if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "Unable to retrieve data ⚠️")
+	}
+	if highest.Valid && a.MaxScore < highest.Float64 {
+		return models.Assessment{}, &ValidationError{Err: fmt.Errorf("max_score %v is below the highest score already entered (%v) ⚠️", a.MaxScore, highest.Float64)}
+	}
+
+	_, err = db.Exec(`UPDATE assessments SET title = ?, type = ?, subject_id = ?, class_id = ?, teacher_id = ?,
+	max_score = ?, date = ?, term = ? WHERE id = ?`,
+		a.Title, a.Type, a.SubjectID, a.ClassID, nullableInt(a.TeacherID), a.MaxScore, a.Date, a.Term, a.ID)
+	if err != nil {
+		return models.Assessment{}, utils.ErrorHandler(err, "ERROR updating assessment ⚠️")
+	}
+	return a, nil
+}
+
+//! Delete assessment (and its scores) Db ops.
+func DeleteAssessmentDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM assessments WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting assessment ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting assessment ⚠️")
+	}
+	if rowsAffected == 0 {
+		return utils.ErrorHandler(err, "Assessment Not Found ⚠️")
+	}
+	return nil
+}
+
+//! Bulk-ENTER scores for an assessment DB ops. (re-entering a student's score overwrites it)
+func AddScoresDbHandler(assessmentId int, newScores []models.Score) ([]models.Score, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	var maxScore float64
+	var className string
+	err = db.QueryRow("SELECT a.max_score, c.name FROM assessments a JOIN classes c ON c.id = a.class_id WHERE a.id = ?", assessmentId).
+		Scan(&maxScore, &className)
+	if err == sql.ErrNoRows {
+		return nil, utils.ErrorHandler(err, "Assessment Not Found! ⚠️")
+	} else if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	// scores are only accepted for students of the assessed class
+	rows, err := db.Query("SELECT id FROM students WHERE class = ?", className)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	inClass := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		inClass[id] = true
+	}
+	rows.Close()
+
+	for _, s := range newScores {
+		if !inClass[s.StudentID] {
+			return nil, fmt.Errorf("student %d is not in class %s ⚠️", s.StudentID, className)
+		}
+		if s.Score < 0 || s.Score > maxScore {
+			return nil, fmt.Errorf("score %g for student %d is outside 0-%g ⚠️", s.Score, s.StudentID, maxScore)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO scores (assessment_id, student_id, score, remarks) VALUES (?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE score = VALUES(score), remarks = VALUES(remarks)`)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	for _, s := range newScores {
+		if _, err := stmt.Exec(assessmentId, s.StudentID, s.Score, s.Remarks); err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR entering scores ⚠️")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return getScores(db, assessmentId)
+}
+
+//! GET scores of an assessment DB ops.
+func GetScoresDbHandler(assessmentId int) ([]models.Score, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "assessments", assessmentId); err != nil {
+		return nil, utils.ErrorHandler(err, "Assessment Not Found! ⚠️")
+	}
+	return getScores(db, assessmentId)
+}
+
+func getScores(db *sql.DB, assessmentId int) ([]models.Score, error) {
+	rows, err := db.Query("SELECT id, assessment_id, student_id, score, remarks FROM scores WHERE assessment_id = ? ORDER BY student_id", assessmentId)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving scores! ⚠️")
+	}
+	defer rows.Close()
+
+	scores := []models.Score{}
+	for rows.Next() {
+		var s models.Score
+		if err := rows.Scan(&s.ID, &s.AssessmentID, &s.StudentID, &s.Score, &s.Remarks); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		scores = append(scores, s)
+	}
+	return scores, nil
+}
+
+//! GET report card of a student DB ops. - average % per subject per term, ?term= to narrow it down
+func GetReportCardDbHandler(studentId int, r *http.Request) ([]models.ReportCardLine, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err != nil {
+		return nil, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	}
+
+	qry := `SELECT su.name, a.term, COUNT(*), AVG(sc.score / a.max_score * 100)
+	FROM scores sc
+	JOIN assessments a ON a.id = sc.assessment_id
+	JOIN subjects su ON su.id = a.subject_id
+	WHERE sc.student_id = ?`
+	args := []any{studentId}
+	qry, args = AddFiltersFor(r, qry, args, map[string]string{"term": "a.term", "type": "a.type"})
+	qry += " GROUP BY su.name, a.term ORDER BY a.term, su.name"
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. building report card! ⚠️")
+	}
+	defer rows.Close()
+
+	lines := []models.ReportCardLine{}
+	for rows.Next() {
+		var l models.ReportCardLine
+		if err := rows.Scan(&l.Subject, &l.Term, &l.Assessments, &l.Average); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		l.Grade = LetterGrade(l.Average)
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+//! GET grade distribution of a class DB ops. - ?assessment_id=&subject_id=&term=&type=
+func GetGradeDistributionDbHandler(classId int, r *http.Request) ([]models.GradeBucket, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, classId); err != nil {
+		return nil, err
+	}
+
+	qry := "SELECT " + gradeCaseSQL("sc.score / a.max_score * 100") + ` AS grade, COUNT(*)
+	FROM scores sc JOIN assessments a ON a.id = sc.assessment_id
+	WHERE a.class_id = ?`
+	args := []any{classId}
+	qry, args = AddFiltersFor(r, qry, args, map[string]string{
+		"assessment_id": "a.id",
+		"subject_id":    "a.subject_id",
+		"term":          "a.term",
+		"type":          "a.type",
+	})
+	qry += " GROUP BY grade"
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. building distribution! ⚠️")
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var grade string
+		var count int
+		if err := rows.Scan(&grade, &count); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		counts[grade] = count
+	}
+
+	// every grade shows up, empty ones with 0
+	buckets := make([]models.GradeBucket, len(gradeScale))
+	for i, g := range gradeScale {
+		buckets[i] = models.GradeBucket{Grade: g.Grade, Count: counts[g.Grade]}
+	}
+	return buckets, nil
+}
@@ -0,0 +1,313 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const guardianColumns = "g.id, g.first_name, g.last_name, g.relation, g.phone, g.email, g.is_primary"
+
+// query-param -> db-column
+var guardianFilterParams = map[string]string{
+	"first_name": "g.first_name",
+	"last_name":  "g.last_name",
+	"relation":   "g.relation",
+	"phone":      "g.phone",
+	"email":      "g.email",
+	"is_primary": "g.is_primary = (? IN ('1', 'true'))",
+	"student":    "g.id IN (SELECT sg.guardian_id FROM student_guardians sg WHERE sg.student_id = ?)",
+}
+
+// sortby-field -> db-column
+var guardianSortFields = map[string]string{
+	"first_name": "g.first_name",
+	"last_name":  "g.last_name",
+	"relation":   "g.relation",
+	"email":      "g.email",
+	"is_primary": "g.is_primary",
+}
+
+func IsValidRelation(relation string) bool {
+	switch relation {
+	case models.RelationMother, models.RelationFather, models.RelationGrandparent,
+		models.RelationSibling, models.RelationGuardian, models.RelationOther:
+		return true
+	}
+	return false
+}
+
+// queryGuardians runs a guardian SELECT and fills in each guardian's student_ids
+func queryGuardians(db queryer, qry string, args ...any) ([]models.Guardian, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving guardians! ⚠️")
+	}
+	guardians := []models.Guardian{}
+	for rows.Next() {
+		var g models.Guardian
+		if err := rows.Scan(&g.ID, &g.FirstName, &g.LastName, &g.Relation, &g.Phone, &g.Email, &g.IsPrimary); err != nil {
+			rows.Close()
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		guardians = append(guardians, g)
+	}
+	rows.Close()
+	if len(guardians) == 0 {
+		return guardians, nil
+	}
+
+	ids := make([]any, len(guardians))
+	index := make(map[int]int, len(guardians))
+	for i, g := range guardians {
+		ids[i] = g.ID
+		index[g.ID] = i
+	}
+	rows, err = db.Query("SELECT guardian_id, student_id FROM student_guardians WHERE guardian_id IN ("+placeholders(len(ids))+") ORDER BY student_id", ids...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving guardian links! ⚠️")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guardianId, studentId int
+		if err := rows.Scan(&guardianId, &studentId); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		g := &guardians[index[guardianId]]
+		g.StudentIDs = append(g.StudentIDs, studentId)
+	}
+	return guardians, nil
+}
+
+func getGuardian(db queryer, id int) (models.Guardian, error) {
+	guardians, err := queryGuardians(db, "SELECT "+guardianColumns+" FROM guardians g WHERE g.id = ?", id)
+	if err != nil {
+		return models.Guardian{}, err
+	}
+	if len(guardians) == 0 {
+		return models.Guardian{}, utils.ErrorHandler(sql.ErrNoRows, "Guardian Not Found! ⚠️")
+	}
+	return guardians[0], nil
+}
+
+// linkStudents replaces the students of a guardian; every student has to exist
+func linkStudents(tx *sql.Tx, guardianId int, studentIds []int) error {
+	if _, err := tx.Exec("DELETE FROM student_guardians WHERE guardian_id = ?", guardianId); err != nil {
+		return utils.ErrorHandler(err, "ERROR unlinking students ⚠️")
+	}
+	for _, studentId := range studentIds {
+		var found int
+		err := tx.QueryRow("SELECT id FROM students WHERE id = ?", studentId).Scan(&found)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("student %d does not exist ⚠️", studentId)
+		} else if err != nil {
+			return utils.ErrorHandler(err, "DB Query Error! ⚠️")
+		}
+		if _, err := tx.Exec("INSERT IGNORE INTO student_guardians (student_id, guardian_id) VALUES (?, ?)", studentId, guardianId); err != nil {
+			return utils.ErrorHandler(err, "ERROR linking student ⚠️")
+		}
+	}
+	return nil
+}
+
+//! GET All guardians DB ops.
+func GetGuardiansDbHandler(r *http.Request) ([]models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT " + guardianColumns + " FROM guardians g WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, guardianFilterParams)
+	qry = AddSortingFor(r, qry, guardianSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	return queryGuardians(db, qry, args...)
+}
+
+//! GET single guardian by ID DB ops.
+func GetGuardianDbHandler(id int) (models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	return getGuardian(db, id)
+}
+
+//! GET guardians of a student DB ops. - same filters as /guardians
+func GetStudentGuardiansDbHandler(studentId int, r *http.Request) ([]models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err != nil {
+		return nil, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	}
+
+	qry := "SELECT " + guardianColumns + " FROM guardians g JOIN student_guardians sg ON sg.guardian_id = g.id WHERE sg.student_id = ?"
+	args := []any{studentId}
+	qry, args = AddFiltersFor(r, qry, args, guardianFilterParams)
+	qry = AddSortingFor(r, qry, guardianSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	return queryGuardians(db, qry, args...)
+}
+
+//! Add / POST guardians DB ops. - all of them (and their links) or none
+func AddGuardiansDbHandler(newGuardians []models.Guardian) ([]models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(GenerateInsertQry("guardians", models.Guardian{}))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	addedGuardians := make([]models.Guardian, len(newGuardians))
+	for i, newGuardian := range newGuardians {
+		res, err := stmt.Exec(GetStructVals(newGuardian)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		newGuardian.ID = int(lastId)
+		if err := linkStudents(tx, newGuardian.ID, newGuardian.StudentIDs); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		addedGuardians[i] = newGuardian
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return addedGuardians, nil
+}
+
+// saveGuardian writes all columns of an existing guardian; links are only replaced when studentIds != nil
+func saveGuardian(db *sql.DB, g models.Guardian, studentIds []int) (models.Guardian, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	_, err = tx.Exec("UPDATE guardians SET first_name = ?, last_name = ?, relation = ?, phone = ?, email = ?, is_primary = ? WHERE id = ?",
+		g.FirstName, g.LastName, g.Relation, g.Phone, g.Email, g.IsPrimary, g.ID)
+	if err != nil {
+		tx.Rollback()
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR updating guardian ⚠️")
+	}
+	if studentIds != nil {
+		if err := linkStudents(tx, g.ID, studentIds); err != nil {
+			tx.Rollback()
+			return models.Guardian{}, err
+		}
+	}
+
+	saved, err := getGuardian(tx, g.ID)
+	if err != nil {
+		tx.Rollback()
+		return models.Guardian{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return saved, nil
+}
+
+//! Update/PUT guardian Db ops. - student_ids omitted => links stay as they are
+func UpdateGuardianDbHandler(id int, updatedGuardian models.Guardian) (models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "guardians", id); err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "Guardian Not Found ⚠️")
+	}
+	updatedGuardian.ID = id
+	return saveGuardian(db, updatedGuardian, updatedGuardian.StudentIDs)
+}
+
+//! PATCH single guardian Db ops. - {"student_ids": [..]} replaces the linked students
+func PatchGuardianDbHandler(id int, updates map[string]any) (models.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	existingGuardian, err := getGuardian(db, id)
+	if err != nil {
+		return models.Guardian{}, err
+	}
+
+	// student_ids has no db-column, ApplyUpdates would skip it
+	var studentIds []int
+	if raw, ok := updates["student_ids"]; ok {
+		list, ok := raw.([]any)
+		if !ok {
+			return models.Guardian{}, fmt.Errorf("student_ids must be a list of IDs ⚠️")
+		}
+		studentIds = []int{}
+		for _, v := range list {
+			studentId, err := intFromUpdate(v)
+			if err != nil {
+				return models.Guardian{}, fmt.Errorf("invalid student ID %v ⚠️", v)
+			}
+			studentIds = append(studentIds, studentId)
+		}
+	}
+
+	if err := ApplyUpdates(&existingGuardian, updates); err != nil {
+		return models.Guardian{}, utils.ErrorHandler(err, "ERROR: Invalid value in update! ⚠️")
+	}
+	return saveGuardian(db, existingGuardian, studentIds)
+}
+
+//! Delete guardian Db ops. - the student links go with it
+func DeleteGuardianDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM guardians WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting guardian ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting guardian ⚠️")
+	}
+	if rowsAffected == 0 {
+		return utils.ErrorHandler(err, "Guardian Not Found ⚠️")
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package sqlconnect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// importer upserts rows of T into table, matching existing rows on email.
+// Inserts go through the same GenerateInsertQry/GetStructVals path as POST /teachers.
+type importer[T any] struct {
+	table                      string
+	createdEvent, updatedEvent string // written to the outbox along with every imported row
+	// optional hooks, all run on the import's db/tx
+	validate func(db execQueryer, row T) error
+	created  func(db execQueryer, id int, row T) error
+	updating func(db execQueryer, id int, row T) error // may refuse an update
+}
+
+var teacherImporter = importer[models.Teacher]{
+	table:        "teachers",
+	createdEvent: events.TeacherCreated,
+	updatedEvent: events.TeacherUpdated,
+	validate: func(db execQueryer, t models.Teacher) error { return classByName(db, t.Class) },
+}
+
+var studentImporter = importer[models.Student]{
+	table:        "students",
+	createdEvent: events.StudentCreated,
+	updatedEvent: events.StudentUpdated,
+	validate: func(db execQueryer, s models.Student) error { return classByName(db, s.Class) },
+	created: func(db execQueryer, id int, s models.Student) error {
+		return openEnrollment(db, id, s.Class, today())
+	},
+	// class changes have to go through the enrollment history
+	updating: func(db execQueryer, id int, s models.Student) error {
+		var class string
+		if err := db.QueryRow("SELECT class FROM students WHERE id = ?", id).Scan(&class); err != nil {
+			return utils.ErrorHandler(err, "DB Query Error! ⚠️")
+		}
+		if class != s.Class {
+			return fmt.Errorf("student %d is in class %s, use POST /students/%d/transfer to move them ⚠️", id, class, id)
+		}
+		return nil
+	},
+}
+
+// classByName - every teacher and student is in a class, the same as on POST
+func classByName(db execQueryer, name string) error {
+	if name == "" {
+		return fmt.Errorf("class is required ⚠️")
+	}
+	var id int
+	err := db.QueryRow("SELECT id FROM classes WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unknown class %q ⚠️", name)
+	}
+	return err
+}
+
+// emailOf reads the db:"email" field of a model
+func emailOf(model any) string {
+	modelVal := reflect.ValueOf(model)
+	for i := 0; i < modelVal.NumField(); i++ {
+		if strings.Split(modelVal.Type().Field(i).Tag.Get("db"), ",")[0] == "email" {
+			return modelVal.Field(i).String()
+		}
+	}
+	return ""
+}
+
+// withID sets the ID field of a model
+func withID[T any](row T, id int) T {
+	reflect.ValueOf(&row).Elem().FieldByName("ID").SetInt(int64(id))
+	return row
+}
+
+// upsert writes a single row (plus its event) and reports whether it was created or updated
+func (im importer[T]) upsert(db execQueryer, row T) (int, string, error) {
+	if im.validate != nil {
+		if err := im.validate(db, row); err != nil {
+			return 0, "", err
+		}
+	}
+
+	var id int
+	err := db.QueryRow("SELECT id FROM "+im.table+" WHERE email = ?", emailOf(row)).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, "", utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	if err == sql.ErrNoRows {
+		res, err := db.Exec(GenerateInsertQry(im.table, row), GetStructVals(row)...)
+		if err != nil {
+			return 0, "", utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return 0, "", utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		id = int(lastId)
+		if im.created != nil {
+			if err := im.created(db, id, row); err != nil {
+				return 0, "", err
+			}
+		}
+		if err := addOutboxEvent(db, events.New(im.createdEvent, id, withID(row, id))); err != nil {
+			return 0, "", err
+		}
+		return id, models.ImportCreated, nil
+	}
+
+	if im.updating != nil {
+		if err := im.updating(db, id, row); err != nil {
+			return 0, "", err
+		}
+	}
+	columns := dbColumns(row)
+	for i := range columns {
+		columns[i] += " = ?"
+	}
+	_, err = db.Exec("UPDATE "+im.table+" SET "+strings.Join(columns, ", ")+" WHERE id = ?",
+		append(GetStructVals(row), id)...)
+	if err != nil {
+		return 0, "", utils.ErrorHandler(err, "ERROR updating DATA in DB⚠️")
+	}
+	if err := addOutboxEvent(db, events.New(im.updatedEvent, id, withID(row, id))); err != nil {
+		return 0, "", err
+	}
+	return id, models.ImportUpdated, nil
+}
+
+// upsertAlone - best-effort mode: every row is a transaction of its own, so its event can't get lost
+func (im importer[T]) upsertAlone(db *sql.DB, row T) (int, string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	id, status, err := im.upsert(tx, row)
+	if err != nil {
+		tx.Rollback()
+		return 0, "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, "", utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return id, status, nil
+}
+
+// run imports rows (line i+2 of the CSV); rowErrs[i] != nil marks a row that already failed parsing/validation.
+// atomic: one transaction, any failure rolls back everything. best-effort: every good row is kept.
+// report (optional) gets the progress after every row; a cancelled ctx stops the import.
+func (im importer[T]) run(ctx context.Context, rows []T, rowErrs []error, mode string, report func(done, total int)) (models.ImportReport, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.ImportReport{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	result := models.ImportReport{Mode: mode, Rows: make([]models.ImportRowResult, len(rows))}
+
+	var tx *sql.Tx
+	if mode == models.ImportAtomic {
+		tx, err = db.Begin()
+		if err != nil {
+			return models.ImportReport{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+		}
+	}
+
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			if tx != nil {
+				tx.Rollback()
+			}
+			return models.ImportReport{}, err
+		}
+
+		line := models.ImportRowResult{Row: i + 2, Email: emailOf(row)}
+		err := rowErrs[i]
+		if err == nil && tx != nil {
+			line.ID, line.Status, err = im.upsert(tx, row)
+		} else if err == nil {
+			line.ID, line.Status, err = im.upsertAlone(db, row)
+		}
+		if err != nil {
+			line.Status, line.Error = models.ImportFailed, err.Error()
+			result.Failed++
+		} else if line.Status == models.ImportCreated {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		result.Rows[i] = line
+		if report != nil {
+			report(i+1, len(rows))
+		}
+	}
+
+	if tx == nil {
+		result.Committed = true
+		return result, nil
+	}
+	if result.Failed > 0 {
+		tx.Rollback()
+		for i := range result.Rows {
+			if result.Rows[i].Status != models.ImportFailed {
+				result.Rows[i].Status, result.Rows[i].ID = models.ImportSkipped, 0
+			}
+		}
+		result.Created, result.Updated = 0, 0
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ImportReport{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	result.Committed = true
+	return result, nil
+}
+
+//! IMPORT teachers DB ops. - upsert by email
+func ImportTeachersDbHandler(ctx context.Context, rows []models.Teacher, rowErrs []error, mode string, report func(done, total int)) (models.ImportReport, error) {
+	return teacherImporter.run(ctx, rows, rowErrs, mode, report)
+}
+
+//! IMPORT students DB ops. - upsert by email, new students get enrolled in their class
+func ImportStudentsDbHandler(ctx context.Context, rows []models.Student, rowErrs []error, mode string, report func(done, total int)) (models.ImportReport, error) {
+	return studentImporter.run(ctx, rows, rowErrs, mode, report)
+}
@@ -0,0 +1,89 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+)
+
+// ?include=a,b - optional computed fields, each filled in for the WHOLE page with
+// a single aggregate query (never one query per row).
+
+// Includer fills one computed field on every row of a list
+type Includer[T any] func(db *sql.DB, rows []T) error
+
+// ParseIncludes reads ?include=a,b (or include=a&include=b) and rejects unknown names
+func ParseIncludes[T any](r *http.Request, registry map[string]Includer[T]) ([]string, error) {
+	var includes []string
+	for _, param := range r.URL.Query()["include"] {
+		for _, name := range strings.Split(param, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := registry[name]; !ok {
+				return nil, fmt.Errorf("unknown include %q ⚠️", name)
+			}
+			includes = append(includes, name)
+		}
+	}
+	return includes, nil
+}
+
+func applyIncludes[T any](db *sql.DB, rows []T, includes []string, registry map[string]Includer[T]) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	for _, name := range includes {
+		include, ok := registry[name]
+		if !ok {
+			return fmt.Errorf("unknown include %q ⚠️", name)
+		}
+		if err := include(db, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//! Teacher includes
+var TeacherIncludes = map[string]Includer[models.Teacher]{
+	"student_count": includeTeacherStudentCount,
+}
+
+// student_count - distinct students across every class the teacher teaches
+func includeTeacherStudentCount(db *sql.DB, teachers []models.Teacher) error {
+	ids := make([]any, len(teachers))
+	for i, t := range teachers {
+		ids[i] = t.ID
+	}
+
+	rows, err := db.Query(`
+	SELECT tc.teacher_id, COUNT(DISTINCT s.id)
+	FROM `+teacherClassesSQL+` tc JOIN students s ON s.class = tc.class_name
+	WHERE tc.teacher_id IN (`+placeholders(len(ids))+`)
+	GROUP BY tc.teacher_id`, ids...)
+	if err != nil {
+		return fmt.Errorf("ERROR counting students ⚠️")
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var teacherId, count int
+		if err := rows.Scan(&teacherId, &count); err != nil {
+			return fmt.Errorf("ERROR counting students ⚠️")
+		}
+		counts[teacherId] = count
+	}
+
+	// teachers without students still get an explicit 0
+	for i := range teachers {
+		count := counts[teachers[i].ID]
+		teachers[i].StudentCount = &count
+	}
+	return nil
+}
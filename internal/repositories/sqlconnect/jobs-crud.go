@@ -0,0 +1,244 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const jobColumns = `id, type, status, payload, result, error, done, total, attempts, max_attempts,
+	created_at, started_at, finished_at`
+
+// query-param -> db-column
+var jobFilterParams = map[string]string{
+	"status": "status",
+	"type":   "type",
+}
+
+func scanJob(row interface{ Scan(...any) error }) (models.Job, error) {
+	var j models.Job
+	var payload string
+	var result, errMsg, startedAt, finishedAt sql.NullString
+	err := row.Scan(&j.ID, &j.Type, &j.Status, &payload, &result, &errMsg, &j.Done, &j.Total, &j.Attempts, &j.MaxAttempts,
+		&j.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return models.Job{}, err
+	}
+	j.Payload = []byte(payload)
+	if result.Valid {
+		j.Result = []byte(result.String)
+	}
+	j.Error, j.StartedAt, j.FinishedAt = errMsg.String, startedAt.String, finishedAt.String
+	if j.Total > 0 {
+		percent := min(100, j.Done*100/j.Total)
+		j.Progress = &percent
+	}
+	return j, nil
+}
+
+// addJob queues a job - db can be the transaction of what the job is for, so neither exists without the other
+func addJob(db execQueryer, jobType string, payload []byte, maxAttempts int) (int64, error) {
+	res, err := db.Exec("INSERT INTO jobs (type, payload, max_attempts) VALUES (?, ?, ?)", jobType, string(payload), maxAttempts)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR queueing job ⚠️")
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+	}
+	return lastId, nil
+}
+
+//! Add / enqueue a job DB ops.
+func AddJobDbHandler(jobType string, payload []byte, maxAttempts int) (models.Job, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	lastId, err := addJob(db, jobType, payload, maxAttempts)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	job, err := scanJob(db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", lastId))
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return job, nil
+}
+
+//! GET single job DB ops.
+func GetJobDbHandler(id int) (models.Job, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	job, err := scanJob(db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Job{}, utils.ErrorHandler(err, "Job Not Found! ⚠️")
+	} else if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return job, nil
+}
+
+//! GET All jobs DB ops. - ?status=&type=, newest first
+func GetJobsDbHandler(r *http.Request) ([]models.Job, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT " + jobColumns + " FROM jobs WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, jobFilterParams)
+	qry += " ORDER BY id DESC"
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving jobs! ⚠️")
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+//! CLAIM the next queued job DB ops. - ok == false when the queue is empty
+// SKIP LOCKED lets every worker (and every replica) grab a different job.
+// The runner's own ops (claim, heartbeat, finish, retry, sweep) poll all the time - they get its long-lived db.
+func ClaimJobDbHandler(db *sql.DB) (job models.Job, ok bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Job{}, false, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	var id int
+	err = tx.QueryRow(`SELECT id FROM jobs WHERE status = 'queued' AND run_after <= NOW()
+		ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`).Scan(&id)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return models.Job{}, false, nil
+	} else if err != nil {
+		tx.Rollback()
+		return models.Job{}, false, utils.ErrorHandler(err, "ERROR claiming job ⚠️")
+	}
+
+	_, err = tx.Exec(`UPDATE jobs SET status = 'running', attempts = attempts + 1, heartbeat_at = NOW(),
+		started_at = COALESCE(started_at, NOW()) WHERE id = ?`, id)
+	if err != nil {
+		tx.Rollback()
+		return models.Job{}, false, utils.ErrorHandler(err, "ERROR claiming job ⚠️")
+	}
+	job, err = scanJob(tx.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err != nil {
+		tx.Rollback()
+		return models.Job{}, false, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Job{}, false, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return job, true, nil
+}
+
+//! HEARTBEAT of a running job DB ops. - stores the progress, reports whether a cancel was requested
+func JobHeartbeatDbHandler(db *sql.DB, id, done, total int) (cancelRequested bool, err error) {
+	_, err = db.Exec("UPDATE jobs SET heartbeat_at = NOW(), done = ?, total = ? WHERE id = ? AND status = 'running'", done, total, id)
+	if err != nil {
+		return false, utils.ErrorHandler(err, "ERROR updating job ⚠️")
+	}
+	err = db.QueryRow("SELECT cancel_requested FROM jobs WHERE id = ?", id).Scan(&cancelRequested)
+	if err != nil {
+		return false, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return cancelRequested, nil
+}
+
+//! FINISH a job DB ops. - status is succeeded, failed or cancelled
+// Only the attempt that's still running: once the stale sweep re-queued a job, a late worker
+// of an earlier attempt mustn't overwrite what the new one does.
+func FinishJobDbHandler(db *sql.DB, id, attempt int, status string, result []byte, errMsg string) error {
+	var resultVal any
+	if result != nil {
+		resultVal = string(result)
+	}
+	_, err := db.Exec(`UPDATE jobs SET status = ?, result = ?, error = NULLIF(?, ''), finished_at = NOW()
+		WHERE id = ? AND status = 'running' AND attempts = ?`, status, resultVal, errMsg, id, attempt)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR finishing job ⚠️")
+	}
+	return nil
+}
+
+//! RETRY a failed attempt later DB ops. - same as FINISH, only the attempt that's still running
+func RetryJobDbHandler(db *sql.DB, id, attempt int, errMsg string, delay time.Duration) error {
+	_, err := db.Exec(`UPDATE jobs SET status = 'queued', error = ?, heartbeat_at = NULL,
+		run_after = NOW() + INTERVAL ? SECOND WHERE id = ? AND status = 'running' AND attempts = ?`,
+		errMsg, int(delay.Seconds()), id, attempt)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR re-queueing job ⚠️")
+	}
+	return nil
+}
+
+//! CANCEL a job DB ops. - queued jobs are cancelled right away, running ones get flagged for their worker
+func CancelJobDbHandler(id int) (models.Job, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`UPDATE jobs SET
+		cancel_requested = TRUE,
+		finished_at = IF(status = 'queued', NOW(), finished_at),
+		status = IF(status = 'queued', 'cancelled', status)
+		WHERE id = ? AND status IN ('queued', 'running')`, id)
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR cancelling job ⚠️")
+	}
+
+	job, err := scanJob(db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Job{}, utils.ErrorHandler(err, "Job Not Found! ⚠️")
+	} else if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if job.Status == models.JobSucceeded || job.Status == models.JobFailed {
+		return job, fmt.Errorf("job %d already %s ⚠️", id, job.Status)
+	}
+	return job, nil
+}
+
+//! REQUEUE jobs of dead workers DB ops. - running, but no heartbeat for staleAfter
+func RequeueStaleJobsDbHandler(db *sql.DB, staleAfter time.Duration) (int64, error) {
+	// a job that already used up its attempts (or was being cancelled) isn't started again
+	res, err := db.Exec(`UPDATE jobs SET
+		error = 'worker stopped responding',
+		heartbeat_at = NULL,
+		finished_at = IF(cancel_requested OR attempts >= max_attempts, NOW(), NULL),
+		status = CASE WHEN cancel_requested THEN 'cancelled' WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END
+		WHERE status = 'running' AND heartbeat_at < NOW() - INTERVAL ? SECOND`, int(staleAfter.Seconds()))
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR re-queueing stale jobs ⚠️")
+	}
+	return res.RowsAffected()
+}
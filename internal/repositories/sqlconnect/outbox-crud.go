@@ -0,0 +1,94 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// addOutboxEvent stores e in the outbox. db has to be the transaction of the change the event describes -
+// that's the whole point: the event is committed (or rolled back) together with the change.
+func addOutboxEvent(db execQueryer, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR encoding event ⚠️")
+	}
+	_, err = db.Exec("INSERT INTO outbox (event_id, type, aggregate_type, aggregate_id, payload) VALUES (?, ?, ?, ?, ?)",
+		e.ID, e.Type, e.AggregateType, e.AggregateID, string(payload))
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR writing event to the outbox ⚠️")
+	}
+	return nil
+}
+
+//! DISPATCH pending outbox events DB ops. - returns how many were handed to send
+// Only the oldest pending event of each aggregate is taken, so a teacher's events go out in order
+// even when one of them has to be retried. The rows stay locked (SKIP LOCKED for other replicas) until
+// they're marked; if the process dies before the commit they're simply sent again - at-least-once.
+// db is the dispatcher's long-lived one - it polls every second.
+func DispatchOutboxDbHandler(db *sql.DB, limit int, send func(events.Event) error, backoff func(attempts int) time.Duration) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	rows, err := tx.Query(`SELECT o.id, o.payload, o.attempts FROM outbox o
+		WHERE o.dispatched_at IS NULL AND o.next_attempt_at <= NOW()
+		AND NOT EXISTS (SELECT 1 FROM outbox p WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id
+			AND p.dispatched_at IS NULL AND p.id < o.id)
+		ORDER BY o.id LIMIT ? FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		tx.Rollback()
+		return 0, utils.ErrorHandler(err, "ERROR claiming outbox events ⚠️")
+	}
+	type pending struct {
+		id       int64
+		payload  string
+		attempts int
+	}
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.payload, &p.attempts); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return 0, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+
+	for _, p := range batch {
+		e, err := events.Decode([]byte(p.payload))
+		if err == nil {
+			err = send(e)
+		}
+		if err == nil {
+			_, err = tx.Exec("UPDATE outbox SET dispatched_at = NOW(), last_error = NULL WHERE id = ?", p.id)
+		} else {
+			_, err = tx.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = NOW() + INTERVAL ? SECOND WHERE id = ?",
+				err.Error(), int(backoff(p.attempts+1).Seconds()), p.id)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, utils.ErrorHandler(err, "ERROR updating outbox ⚠️")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return len(batch), nil
+}
+
+//! PURGE dispatched outbox events DB ops.
+func PurgeOutboxDbHandler(db *sql.DB, olderThan time.Duration) (int64, error) {
+	res, err := db.Exec("DELETE FROM outbox WHERE dispatched_at < NOW() - INTERVAL ? SECOND", int(olderThan.Seconds()))
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR purging outbox ⚠️")
+	}
+	return res.RowsAffected()
+}
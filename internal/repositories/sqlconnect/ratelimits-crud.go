@@ -0,0 +1,58 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+//! HIT a rate-limit counter DB ops. - returns the hits of the current window (this one included) and of the one before
+// The upsert is atomic and keeps the row locked until the commit, so concurrent replicas never lose a hit.
+// db is the store's long-lived one - this runs on every request.
+func HitRateLimitDbHandler(db *sql.DB, key string, windowStart time.Time, window time.Duration) (current, previous int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	defer tx.Rollback()
+
+	start, prevStart := windowStart.UnixMilli(), windowStart.Add(-window).UnixMilli()
+	_, err = tx.Exec(`INSERT INTO rate_limits (bucket_key, window_start, hits, expires_at) VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE hits = hits + 1`, key, start, windowStart.Add(2*window).UTC())
+	if err != nil {
+		return 0, 0, utils.ErrorHandler(err, "ERROR updating rate-limit ⚠️")
+	}
+
+	rows, err := tx.Query("SELECT window_start, hits FROM rate_limits WHERE bucket_key = ? AND window_start IN (?, ?)", key, start, prevStart)
+	if err != nil {
+		return 0, 0, utils.ErrorHandler(err, "ERROR reading rate-limit ⚠️")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ws int64
+		var hits int
+		if err := rows.Scan(&ws, &hits); err != nil {
+			return 0, 0, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		if ws == start {
+			current = hits
+		} else {
+			previous = hits
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return current, previous, nil
+}
+
+//! PURGE expired rate-limit counters DB ops.
+func PurgeRateLimitsDbHandler(db *sql.DB) (int64, error) {
+	res, err := db.Exec("DELETE FROM rate_limits WHERE expires_at < UTC_TIMESTAMP()")
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR purging rate-limits ⚠️")
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,122 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// Teacher <-> Student relationships are resolved through the shared class,
+// in a single JOIN - filters/sorting/pagination work like the top-level lists.
+
+// ErrTeacherNotFound / ErrStudentNotFound - reported as 404
+var (
+	ErrTeacherNotFound = errors.New("Teacher Not Found! ⚠️")
+	ErrStudentNotFound = errors.New("Student Not Found! ⚠️")
+)
+
+// every (teacher, class-name) pair: the teacher's own class plus their subject assignments
+const teacherClassesSQL = `(
+	SELECT id AS teacher_id, class AS class_name FROM teachers WHERE class <> ''
+	UNION
+	SELECT tsc.teacher_id, c.name FROM teacher_subject_class tsc JOIN classes c ON c.id = tsc.class_id
+)`
+
+//! GET students taught by a teacher DB ops.
+func GetTeacherStudentsDbHandler(teacherId int, r *http.Request) ([]models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "teachers", teacherId); err == sql.ErrNoRows {
+		return nil, ErrTeacherNotFound
+	} else if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	qry := `SELECT DISTINCT s.id, s.first_name, s.last_name, s.email, s.class
+	FROM students s JOIN ` + teacherClassesSQL + ` tc ON tc.class_name = s.class
+	WHERE tc.teacher_id = ?`
+	args := []any{teacherId}
+	qry, args = AddFiltersFor(r, qry, args, withAlias("s", studentFilterParams))
+	qry = addSortingByID(r, qry, withAlias("s", studentSortFields), "s.id")
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	students := []models.Student{}
+	for rows.Next() {
+		var s models.Student
+		if err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		students = append(students, s)
+	}
+	return students, nil
+}
+
+//! GET teachers of a student DB ops.
+func GetStudentTeachersDbHandler(studentId int, r *http.Request) ([]models.Teacher, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "students", studentId); err == sql.ErrNoRows {
+		return nil, ErrStudentNotFound
+	} else if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	qry := `SELECT DISTINCT t.id, t.first_name, t.last_name, t.email, t.class, t.subject
+	FROM teachers t
+	JOIN ` + teacherClassesSQL + ` tc ON tc.teacher_id = t.id
+	JOIN students s ON s.class = tc.class_name
+	WHERE s.id = ?`
+	args := []any{studentId}
+	qry, args = AddFiltersFor(r, qry, args, teacherFilterParams("t"))
+	qry = addSortingByID(r, qry, withAlias("t", teacherSortFields), "t.id")
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	teachers := []models.Teacher{}
+	for rows.Next() {
+		var t models.Teacher
+		if err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		teachers = append(teachers, t)
+	}
+	return teachers, nil
+}
+
+// addSortingByID sorts like AddSortingFor and then by idColumn - without a total order
+// the pages of AddPagination could skip or repeat rows
+func addSortingByID(r *http.Request, qry string, validFields map[string]string, idColumn string) string {
+	sorted := AddSortingFor(r, qry, validFields)
+	if sorted == qry {
+		return qry + " ORDER BY " + idColumn
+	}
+	return sorted + ", " + idColumn
+}
+
+// rowExists returns sql.ErrNoRows if the table has no row with that id
+func rowExists(db *sql.DB, table string, id int) error {
+	var found int
+	return db.QueryRow("SELECT id FROM "+table+" WHERE id = ?", id).Scan(&found)
+}
@@ -0,0 +1,81 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+//! COUNT teachers DB ops. - accepts the same filters as GET /teachers
+func CountTeachersDbHandler(r *http.Request) (int, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT COUNT(*) FROM teachers WHERE 1=1"
+	var args []any
+	qry, args = AddFilters(r, qry, args)
+
+	var count int
+	if err := db.QueryRow(qry, args...).Scan(&count); err != nil {
+		return 0, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. counting teachers! ⚠️")
+	}
+	return count, nil
+}
+
+//! GROUP BY stats for teachers DB ops. - ?group_by=subject
+func GetTeacherStatsDbHandler(r *http.Request) ([]models.GroupCount, error) {
+	return groupCounts(r, "teachers", models.Teacher{}, teacherFilterParams(""))
+}
+
+//! GROUP BY stats for students DB ops. - ?group_by=class
+func GetStudentStatsDbHandler(r *http.Request) ([]models.GroupCount, error) {
+	return groupCounts(r, "students", models.Student{}, studentFilterParams)
+}
+
+// groupCounts only groups by columns whitelisted through the model's db-tags,
+// the column name is never taken verbatim from the request.
+func groupCounts(r *http.Request, table string, model any, filters map[string]string) ([]models.GroupCount, error) {
+	groupBy := r.URL.Query().Get("group_by")
+	columns := dbColumns(model)
+	idx := slices.Index(columns, groupBy)
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid group_by %q, expected one of %v ⚠️", groupBy, columns)
+	}
+	column := columns[idx]
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT " + column + ", COUNT(*) FROM " + table + " WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, filters)
+	qry += " GROUP BY " + column + " ORDER BY COUNT(*) DESC, " + column
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving stats! ⚠️")
+	}
+	defer rows.Close()
+
+	stats := []models.GroupCount{}
+	for rows.Next() {
+		var value sql.NullString
+		var gc models.GroupCount
+		if err := rows.Scan(&value, &gc.Count); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		gc.Value = value.String
+		stats = append(stats, gc)
+	}
+	return stats, nil
+}
@@ -0,0 +1,384 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// query-param -> db-column
+var studentFilterParams = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+}
+
+// sortby-field -> db-column
+var studentSortFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+}
+
+//! GET All students DB ops.
+func GetStudentsDbHandler(students []models.Student, r *http.Request) ([]models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT id, first_name, last_name, email, class FROM students WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, studentFilterParams)
+	qry = AddSortingFor(r, qry, studentSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Student
+		err := rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		students = append(students, s)
+	}
+	return students, nil
+}
+
+//! GET single student by ID DB ops.
+func GetStudentDbHandler(id int) (models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	student, err := getStudent(db, id)
+	if err == sql.ErrNoRows {
+		return models.Student{}, utils.ErrorHandler(err, "Student Not Found! ⚠️")
+	} else if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return student, nil
+}
+
+func getStudent(db execQueryer, id int) (models.Student, error) {
+	var s models.Student
+	err := db.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).
+		Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class)
+	return s, err
+}
+
+//! Add / POST students DB ops.
+func AddStudentsDbHandler(newStudents []models.Student) ([]models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(GenerateInsertQry("students", models.Student{}))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	addedStudents := make([]models.Student, len(newStudents))
+	for i, newStudent := range newStudents {
+		res, err := stmt.Exec(GetStructVals(newStudent)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		newStudent.ID = int(lastId)
+
+		// start the student's enrollment history
+		if err := openEnrollment(tx, newStudent.ID, newStudent.Class, today()); err != nil {
+			tx.Rollback()
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				return nil, err
+			}
+			return nil, utils.ErrorHandler(err, "ERROR enrolling student ⚠️")
+		}
+		if err := addOutboxEvent(tx, events.New(events.StudentCreated, newStudent.ID, newStudent)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		addedStudents[i] = newStudent
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return addedStudents, nil
+}
+
+//! Update/PUT student Db ops.
+func UpdateStudentDbHandler(id int, updatedStudent models.Student) (models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	existingStudent, err := getStudent(db, id)
+	if err == sql.ErrNoRows {
+		return models.Student{}, utils.ErrorHandler(err, "Student Not Found ⚠️")
+	} else if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR: Unable to retrieve data ⚠️")
+	}
+	updatedStudent.ID = existingStudent.ID
+	if err := keepClass(existingStudent, updatedStudent.Class); err != nil {
+		return models.Student{}, err
+	}
+
+	if err := saveStudent(db, updatedStudent); err != nil {
+		return models.Student{}, err
+	}
+	return updatedStudent, nil
+}
+
+//! PATCH single student Db ops.
+func PatchSingleStudentDbOps(id int, updates map[string]any) (models.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	existingStudent, err := getStudent(db, id)
+	if err == sql.ErrNoRows {
+		return models.Student{}, utils.ErrorHandler(err, "Student Not Found ⚠️")
+	} else if err != nil {
+		return models.Student{}, utils.ErrorHandler(err, "Unable to retrieve data ⚠️")
+	}
+
+	oldClass := existingStudent.Class
+	if err := ApplyUpdates(&existingStudent, updates); err != nil {
+		return models.Student{}, &ValidationError{Err: err}
+	}
+	if err := keepClass(models.Student{ID: id, Class: oldClass}, existingStudent.Class); err != nil {
+		return models.Student{}, err
+	}
+
+	if err := saveStudent(db, existingStudent); err != nil {
+		return models.Student{}, err
+	}
+	return existingStudent, nil
+}
+
+// keepClass - moving a student happens through the enrollments (POST /students/{id}/transfer),
+// a PUT/PATCH that just overwrote students.class would lose the class history
+func keepClass(existing models.Student, class string) error {
+	if class == existing.Class {
+		return nil
+	}
+	return &ValidationError{Err: fmt.Errorf("student %d is in class %s, use POST /students/%d/transfer to move them ⚠️", existing.ID, existing.Class, existing.ID)}
+}
+
+// saveStudent writes s and its StudentUpdated event in one transaction
+func saveStudent(db *sql.DB, s models.Student) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	_, err = tx.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?",
+		s.FirstName, s.LastName, s.Email, s.Class, s.ID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorHandler(err, "ERROR updating student ⚠️")
+	}
+	if err := addOutboxEvent(tx, events.New(events.StudentUpdated, s.ID, s)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return nil
+}
+
+// ids in PATCH bodies may come as "12" (XML) or 12
+func idFromUpdate(update map[string]any) (int, error) {
+	return intFromUpdate(update["id"])
+}
+
+func intFromUpdate(v any) (int, error) {
+	switch id := v.(type) {
+	case string:
+		return strconv.Atoi(id)
+	case float64:
+		return int(id), nil
+	}
+	return 0, fmt.Errorf("missing or invalid id")
+}
+
+//! PATCH Multiple Students DB ops.
+func PatchStudentsDbHandler(updates []map[string]any) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	for _, update := range updates {
+		id, err := idFromUpdate(update)
+		if err != nil {
+			tx.Rollback()
+			return utils.ErrorHandler(err, "ERROR: Invalid student-ID in update! ⚠️")
+		}
+
+		var studentFromDb models.Student
+		err = tx.QueryRow("SELECT id, first_name, last_name, email, class FROM students WHERE id = ?", id).
+			Scan(&studentFromDb.ID, &studentFromDb.FirstName, &studentFromDb.LastName, &studentFromDb.Email, &studentFromDb.Class)
+		if err != nil {
+			tx.Rollback()
+			if err == sql.ErrNoRows {
+				return utils.ErrorHandler(err, "ERROR: Student not found! ⚠️")
+			}
+			return utils.ErrorHandler(err, "ERROR receiving student! ⚠️")
+		}
+
+		oldClass := studentFromDb.Class
+		if err := ApplyUpdates(&studentFromDb, update); err != nil {
+			tx.Rollback()
+			return &ValidationError{Err: err}
+		}
+		if err := keepClass(models.Student{ID: id, Class: oldClass}, studentFromDb.Class); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE students SET first_name = ?, last_name = ?, email = ?, class = ? WHERE id = ?",
+			studentFromDb.FirstName, studentFromDb.LastName, studentFromDb.Email, studentFromDb.Class, studentFromDb.ID)
+		if err != nil {
+			tx.Rollback()
+			return utils.ErrorHandler(err, "ERROR updating student! ⚠️")
+		}
+		if err := addOutboxEvent(tx, events.New(events.StudentUpdated, studentFromDb.ID, studentFromDb)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return nil
+}
+
+//! Delete Single Student Db ops.
+func DeleteSingleStudentDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	res, err := tx.Exec("DELETE FROM students WHERE id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorHandler(err, "ERROR deleting student ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorHandler(err, "ERROR deleting student ⚠️")
+	}
+	if rowsAffected == 0 {
+		tx.Rollback()
+		return utils.ErrorHandler(err, "Student Not Found ⚠️")
+	}
+	if err := addOutboxEvent(tx, events.New(events.StudentDeleted, id, events.Deleted{ID: id})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return nil
+}
+
+//! Delete Multiple Students Db ops.
+func DeleteStudentsDbHandler(ids []int) ([]int, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction ⚠️")
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM students WHERE id = ?")
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing DELETE statement ⚠️")
+	}
+	defer stmt.Close()
+
+	deletedIds := []int{}
+	for _, id := range ids {
+		res, err := stmt.Exec(id)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR deleting students! ⚠️")
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR retrieving deleted-students ⚠️")
+		}
+		if rowsAffected < 1 {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, fmt.Sprintf("ID %d does not exist ⚠️", id))
+		}
+		if err := addOutboxEvent(tx, events.New(events.StudentDeleted, id, events.Deleted{ID: id})); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		deletedIds = append(deletedIds, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return deletedIds, nil
+}
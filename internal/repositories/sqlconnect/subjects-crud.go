@@ -0,0 +1,314 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// query-param -> db-column
+var subjectFilterParams = map[string]string{
+	"name": "name",
+	"code": "code",
+}
+
+// sortby-field -> db-column
+var subjectSortFields = map[string]string{
+	"name": "name",
+	"code": "code",
+}
+
+// ErrSubjectNotFound - reported as 404
+var ErrSubjectNotFound = errors.New("Subject Not Found ⚠️")
+
+// ErrSubjectTaken - another subject already has the name or code, reported as 409
+var ErrSubjectTaken = errors.New("a subject with that name or code already exists ⚠️")
+
+// ErrSubjectInUse - the subject is still assigned to a teacher/class, reported as 409
+var ErrSubjectInUse = errors.New("subject is still assigned, unassign it first ⚠️")
+
+// subjectWriteError maps a taken name/code, everything else is a database error
+func subjectWriteError(err error, msg string) error {
+	if isMySQLError(err, errDuplicateEntry) {
+		return ErrSubjectTaken
+	}
+	return utils.ErrorHandler(err, msg)
+}
+
+//! GET All subjects DB ops.
+func GetSubjectsDbHandler(r *http.Request) ([]models.Subject, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := "SELECT id, name, code FROM subjects WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, subjectFilterParams)
+	qry = AddSortingFor(r, qry, subjectSortFields)
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	subjects := []models.Subject{}
+	for rows.Next() {
+		var s models.Subject
+		if err := rows.Scan(&s.ID, &s.Name, &s.Code); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		subjects = append(subjects, s)
+	}
+	return subjects, nil
+}
+
+//! GET single subject by ID DB ops.
+func GetSubjectDbHandler(id int) (models.Subject, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	var s models.Subject
+	err = db.QueryRow("SELECT id, name, code FROM subjects WHERE id = ?", id).Scan(&s.ID, &s.Name, &s.Code)
+	if err == sql.ErrNoRows {
+		return models.Subject{}, ErrSubjectNotFound
+	} else if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return s, nil
+}
+
+//! Add / POST subjects DB ops.
+func AddSubjectsDbHandler(newSubjects []models.Subject) ([]models.Subject, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	// all or nothing - a duplicate halfway through mustn't leave the first half added
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	stmt, err := tx.Prepare(GenerateInsertQry("subjects", models.Subject{}))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	addedSubjects := make([]models.Subject, len(newSubjects))
+	for i, newSubject := range newSubjects {
+		res, err := stmt.Exec(GetStructVals(newSubject)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, subjectWriteError(err, "ERROR inserting DATA into DB⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		newSubject.ID = int(lastId)
+		addedSubjects[i] = newSubject
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return addedSubjects, nil
+}
+
+//! Update/PUT subject Db ops.
+func UpdateSubjectDbHandler(id int, updatedSubject models.Subject) (models.Subject, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "subjects", id); err == sql.ErrNoRows {
+		return models.Subject{}, ErrSubjectNotFound
+	} else if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	_, err = db.Exec("UPDATE subjects SET name = ?, code = ? WHERE id = ?", updatedSubject.Name, updatedSubject.Code, id)
+	if err != nil {
+		return models.Subject{}, subjectWriteError(err, "ERROR updating subject ⚠️")
+	}
+	updatedSubject.ID = id
+	return updatedSubject, nil
+}
+
+//! PATCH single subject Db ops. - validate checks the merged subject before it's saved
+func PatchSubjectDbHandler(id int, updates map[string]any, validate func(models.Subject) error) (models.Subject, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	var existingSubject models.Subject
+	err = db.QueryRow("SELECT id, name, code FROM subjects WHERE id = ?", id).
+		Scan(&existingSubject.ID, &existingSubject.Name, &existingSubject.Code)
+	if err == sql.ErrNoRows {
+		return models.Subject{}, ErrSubjectNotFound
+	} else if err != nil {
+		return models.Subject{}, utils.ErrorHandler(err, "Unable to retrieve data ⚠️")
+	}
+
+	if err := ApplyUpdates(&existingSubject, updates); err != nil {
+		return models.Subject{}, &ValidationError{Err: err}
+	}
+	if err := validate(existingSubject); err != nil {
+		return models.Subject{}, &ValidationError{Err: err}
+	}
+
+	_, err = db.Exec("UPDATE subjects SET name = ?, code = ? WHERE id = ?", existingSubject.Name, existingSubject.Code, existingSubject.ID)
+	if err != nil {
+		return models.Subject{}, subjectWriteError(err, "ERROR updating subject ⚠️")
+	}
+	return existingSubject, nil
+}
+
+//! Delete subject Db ops.
+func DeleteSubjectDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	// the FK on teacher_subject_class refuses to drop a subject that is still assigned
+	res, err := db.Exec("DELETE FROM subjects WHERE id = ?", id)
+	if isMySQLError(err, errRowIsReferenced) {
+		return ErrSubjectInUse
+	} else if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting subject ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting subject ⚠️")
+	}
+	if rowsAffected == 0 {
+		return ErrSubjectNotFound
+	}
+	return nil
+}
+
+//! GET assignments of a teacher DB ops.
+func GetTeacherAssignmentsDbHandler(teacherId int) ([]models.Assignment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "teachers", teacherId); err != nil {
+		return nil, utils.ErrorHandler(err, "Teacher Not Found! ⚠️")
+	}
+
+	rows, err := db.Query(`
+	SELECT tsc.id, tsc.teacher_id, tsc.subject_id, tsc.class_id, su.name, c.name
+	FROM teacher_subject_class tsc
+	JOIN subjects su ON su.id = tsc.subject_id
+	JOIN classes c ON c.id = tsc.class_id
+	WHERE tsc.teacher_id = ? ORDER BY c.name, su.name`, teacherId)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving data! ⚠️")
+	}
+	defer rows.Close()
+
+	assignments := []models.Assignment{}
+	for rows.Next() {
+		var a models.Assignment
+		if err := rows.Scan(&a.ID, &a.TeacherID, &a.SubjectID, &a.ClassID, &a.Subject, &a.Class); err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+//! Assign subjects/classes to a teacher DB ops. (already existing assignments are kept as they are)
+func AddTeacherAssignmentsDbHandler(teacherId int, newAssignments []models.Assignment) ([]models.Assignment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "teachers", teacherId); err != nil {
+		return nil, utils.ErrorHandler(err, "Teacher Not Found! ⚠️")
+	}
+	for _, a := range newAssignments {
+		if err := rowExists(db, "subjects", a.SubjectID); err != nil {
+			return nil, utils.ErrorHandler(err, fmt.Sprintf("Subject %d Not Found! ⚠️", a.SubjectID))
+		}
+		if err := rowExists(db, "classes", a.ClassID); err != nil {
+			return nil, utils.ErrorHandler(err, fmt.Sprintf("Class %d Not Found! ⚠️", a.ClassID))
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	// not INSERT IGNORE - that would turn a foreign-key violation into a warning and drop the row silently
+	stmt, err := tx.Prepare(`INSERT INTO teacher_subject_class (teacher_id, subject_id, class_id) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE teacher_id = teacher_id`)
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "ERROR preparing SQL Query ⚠️")
+	}
+	defer stmt.Close()
+
+	for _, a := range newAssignments {
+		_, err := stmt.Exec(teacherId, a.SubjectID, a.ClassID)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR assigning subject - unknown subject or class? ⚠️")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return GetTeacherAssignmentsDbHandler(teacherId)
+}
+
+//! Unassign a subject/class from a teacher DB ops.
+func DeleteTeacherAssignmentDbHandler(teacherId, assignmentId int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM teacher_subject_class WHERE id = ? AND teacher_id = ?", assignmentId, teacherId)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR unassigning subject ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR unassigning subject ⚠️")
+	}
+	if rowsAffected == 0 {
+		return utils.ErrorHandler(err, "Assignment Not Found ⚠️")
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/iamskyy111/go-rest-api/internal/events"
 	"github.com/iamskyy111/go-rest-api/internal/models"
 	"github.com/iamskyy111/go-rest-api/pkg/utils"
 )
@@ -18,62 +19,43 @@ func IsValidSortOrder(order string)bool{
 	return order=="asc" || order=="desc"
 }
 
-func IsValidSortField(field string)bool{
-	//💡 Map[][] is faster than a []slice or an []array
-	validFields:=map[string]bool{
-		"first_name":true,
-		"last_name":true,
-		"email":true,
-		"class":true,
-		"subject":true,
-	}
-	return validFields[field]
-}
-
 //! Advanced Sorting Technique (util fx)
 func AddSorting(r *http.Request, qry string) string {
-	sortParams := r.URL.Query()["sortby"]
-
-	// if params are not empty, then..
-	if len(sortParams) > 0 {
-		qry += " ORDER BY"
+	return AddSortingFor(r, qry, teacherSortFields)
+}
 
-		for i, param := range sortParams {
-			parts := strings.Split(param, ":")
-			if len(parts) != 2 {
-				continue
-			}
-			field, order := parts[0], parts[1]
-			if !IsValidSortField(field) || !IsValidSortOrder(order) {
-				continue
-			}
-			if i > 0 {
-				qry += ","
-			}
-			qry += " " + field + " " + order
-		}
-	}
-	return qry
+// sortby-field -> db-column
+var teacherSortFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"class":      "class",
+	"subject":    "subject",
 }
 
 //! Advanced Filtering Technique (util fx)
 func AddFilters(r *http.Request, qry string, args []any) (string, []any) {
-	params := map[string]string{
-		"first_name": "first_name",
-		"last_name":  "last_name",
-		"email":      "email",
-		"class":      "class",
-		"subject":    "subject",
-	}
-
-	for param, dbField := range params {
-		val := r.URL.Query().Get(param)
-		if val != "" {
-			qry += " AND " + dbField + " = ?"
-			args = append(args, val)
+	return AddFiltersFor(r, qry, args, teacherFilterParams(""))
+}
+
+// query-param -> db-column, alias qualifies the columns for JOIN queries ("t" -> t.class)
+func teacherFilterParams(alias string) map[string]string {
+	col := func(column string) string {
+		if alias == "" {
+			return column
 		}
+		return alias + "." + column
+	}
+	return map[string]string{
+		"first_name": col("first_name"),
+		"last_name":  col("last_name"),
+		"email":      col("email"),
+		"class":      col("class"),
+		// primary subject OR any subject the teacher is assigned to
+		"subject": "(" + col("subject") + " = ? OR " + col("id") + ` IN (
+			SELECT tsc.teacher_id FROM teacher_subject_class tsc
+			JOIN subjects su ON su.id = tsc.subject_id WHERE su.name = ?))`,
 	}
-	return qry, args
 }
 
 //! GET All teachers DB ops.
@@ -93,6 +75,9 @@ func GetTeachersDbHandler(teachers []models.Teacher,r *http.Request) ([]models.T
 		// Advanced Sorting f(x)
 		qry = AddSorting(r, qry)
 
+		// Pagination f(x)
+		qry, args = AddPagination(r, qry, args)
+
 
 	defer db.Close()
 
@@ -114,12 +99,22 @@ func GetTeachersDbHandler(teachers []models.Teacher,r *http.Request) ([]models.T
 		}
 		teachers = append(teachers, t)
 	}
+
+	// Optional computed fields (?include=student_count)
+	includes, err := ParseIncludes(r, TeacherIncludes)
+	if err != nil {
+		return nil, err
+	}
+	err = applyIncludes(db, teachers, includes, TeacherIncludes)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR computing included fields! ⚠️")
+	}
 	return teachers, err
 }
 
 
-//! GET single teacher by ID DB ops.
-func GetTeacherDbHandler(id int) (models.Teacher, error) {
+//! GET single teacher by ID DB ops. (includes - optional computed fields, see TeacherIncludes)
+func GetTeacherDbHandler(id int, includes ...string) (models.Teacher, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		//http.Error(w, "ERROR connecting to DATABASE ⚠️", http.StatusInternalServerError)
@@ -138,7 +133,13 @@ func GetTeacherDbHandler(id int) (models.Teacher, error) {
 		//http.Error(w, "DB Query Error! ⚠️", http.StatusInternalServerError)
 		return models.Teacher{},  utils.ErrorHandler(err,  "DB Query Error! ⚠️")
 	}
-	return teacher, nil
+
+	teachers := []models.Teacher{teacher}
+	err = applyIncludes(db, teachers, includes, TeacherIncludes)
+	if err != nil {
+		return models.Teacher{}, utils.ErrorHandler(err, "ERROR computing included fields! ⚠️")
+	}
+	return teachers[0], nil
 }
 
 // Add / POST teachers DB Ops.
@@ -150,9 +151,16 @@ func AddTeachersDbHandler(newTeachers []models.Teacher) ([]models.Teacher, error
 
 	defer db.Close() // Don't forget to close the db.
 
+	// one transaction, so the outbox gets exactly the events of the teachers that were added
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
 	//stmt, err := db.Prepare("INSERT INTO teachers (first_name, last_name, email, class, subject) VALUES(?,?,?,?,?)")
-	stmt, err := db.Prepare(GenerateInsertQry(models.Teacher{}))
+	stmt, err := tx.Prepare(GenerateInsertQry("teachers", models.Teacher{}))
 	if err != nil {
+		tx.Rollback()
 		return nil, utils.ErrorHandler(err,  "ERROR preparing SQL Query ⚠️")
 	}
 	defer stmt.Close() // Don't forget to close the stmt.
@@ -163,20 +171,30 @@ func AddTeachersDbHandler(newTeachers []models.Teacher) ([]models.Teacher, error
 		values:= GetStructVals(newTeacher)
 		res, err := stmt.Exec(values...)
 		if err != nil {
+			tx.Rollback()
 			fmt.Println("ERROR:", err)
 			return nil, utils.ErrorHandler(err,  "ERROR inserting DATA into DB⚠️")
 		}
 		lastId, err := res.LastInsertId()
 		if err != nil {
+			tx.Rollback()
 			return nil, utils.ErrorHandler(err,  "ERROR getting last-inserted-id⚠️")
 		}
 		newTeacher.ID = int(lastId)
 		addedTeachers[i] = newTeacher
+		if err := addOutboxEvent(tx, events.New(events.TeacherCreated, newTeacher.ID, newTeacher)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
 	}
 	return addedTeachers, nil
 }
 
-func GenerateInsertQry(model any)string{
+func GenerateInsertQry(table string, model any)string{
 	modelType:=reflect.TypeOf(model)
 	var columns, placeholders string
 	for i := 0; i < modelType.NumField(); i++ {
@@ -193,8 +211,8 @@ func GenerateInsertQry(model any)string{
 			placeholders+="?"
 		}
 	}
-	fmt.Printf("INSERT INTO teachers (%s) VALUES (%s)\n",columns,placeholders)
-	return fmt.Sprintf("INSERT INTO teachers (%s) VALUES (%s)",columns,placeholders)
+	fmt.Printf("INSERT INTO %s (%s) VALUES (%s)\n",table,columns,placeholders)
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",table,columns,placeholders)
 }
 
 func GetStructVals(model any)[]any{
@@ -231,11 +249,23 @@ func UpdateTeachersDbHandler(id int, updatedTeacher models.Teacher) (models.Teac
 	}
 	updatedTeacher.ID = existingTeacher.ID
 
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Teacher{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
 	// posting some data - Exec(), retrieving some data - Query()/QueryRow()
-	_,err=db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?",updatedTeacher.FirstName, updatedTeacher.LastName, updatedTeacher.Email,updatedTeacher.Class, updatedTeacher.Subject, updatedTeacher.ID)
+	_,err=tx.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?",updatedTeacher.FirstName, updatedTeacher.LastName, updatedTeacher.Email,updatedTeacher.Class, updatedTeacher.Subject, updatedTeacher.ID)
 	if err!= nil{
+		tx.Rollback()
 		return models.Teacher{}, utils.ErrorHandler(err, "ERROR updating teacher ⚠️",)
 	}
+	if err := addOutboxEvent(tx, events.New(events.TeacherUpdated, updatedTeacher.ID, updatedTeacher)); err != nil {
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Teacher{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
 	return updatedTeacher, nil
 }
 
@@ -289,7 +319,7 @@ func PatchTeachersDbHandler(updates []map[string]any) error {
 			}
 			for i := 0; i < teacherVal.NumField(); i++ {
 				field := teacherType.Field(i)
-				if field.Tag.Get("json") == k+",omitempty" {
+				if field.Tag.Get("json") == k+",omitempty" && field.Tag.Get("db") != "" {
 					fieldVal := teacherVal.Field(i)
 					if fieldVal.CanSet() {
 						val := reflect.ValueOf(v)
@@ -323,6 +353,10 @@ func PatchTeachersDbHandler(updates []map[string]any) error {
 			tx.Rollback()
 			return utils.ErrorHandler(err,"ERROR updating teacher! ⚠️")
 		}
+		if err := addOutboxEvent(tx, events.New(events.TeacherUpdated, teacherFromDb.ID, teacherFromDb)); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	// Commit the transaction
@@ -363,7 +397,7 @@ func PatchSingleTeacherDbOps(id int, updates map[string]any) (models.Teacher, er
 		for i := 0; i < teacherVal.NumField(); i++ {
 			field := teacherType.Field(i)
 			field.Tag.Get("json")
-			if field.Tag.Get("json") == k+",omitempty" {
+			if field.Tag.Get("json") == k+",omitempty" && field.Tag.Get("db") != "" {
 				if teacherVal.Field(i).CanSet() {
 					fieldVal := teacherVal.Field(i)
 					fieldVal.Set(reflect.ValueOf(v).Convert(teacherVal.Field(i).Type()))
@@ -374,10 +408,22 @@ func PatchSingleTeacherDbOps(id int, updates map[string]any) (models.Teacher, er
 
 	// send existingTeacher{} back to the DB for updation
 	// posting some data - Exec(), retrieving some data - Query()/QueryRow()
-	_, err = db.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingTeacher.FirstName, existingTeacher.LastName, existingTeacher.Email, existingTeacher.Class, existingTeacher.Subject, existingTeacher.ID)
+	tx, err := db.Begin()
 	if err != nil {
+		return models.Teacher{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	_, err = tx.Exec("UPDATE teachers SET first_name = ?, last_name = ?, email = ?, class = ?, subject = ? WHERE id = ?", existingTeacher.FirstName, existingTeacher.LastName, existingTeacher.Email, existingTeacher.Class, existingTeacher.Subject, existingTeacher.ID)
+	if err != nil {
+		tx.Rollback()
 		return models.Teacher{}, utils.ErrorHandler(err,"ERROR updating teacher ⚠️")
 	}
+	if err := addOutboxEvent(tx, events.New(events.TeacherUpdated, existingTeacher.ID, existingTeacher)); err != nil {
+		tx.Rollback()
+		return models.Teacher{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Teacher{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
 	return existingTeacher, nil
 }
 
@@ -390,21 +436,36 @@ func DeleteSingleTeacherDbHandler( id int) error {
 	}
 	defer db.Close() // always close() the db.
 
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
 	// res/result - confirmation
-	res, err := db.Exec("DELETE FROM teachers WHERE id = ?", id)
+	res, err := tx.Exec("DELETE FROM teachers WHERE id = ?", id)
 	if err != nil {
+		tx.Rollback()
 		return utils.ErrorHandler(err,"ERROR deleting teacher ⚠️")
 	}
 
 	fmt.Println(res.RowsAffected()) // for our info.
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		return utils.ErrorHandler(err,"ERROR deleting teacher ⚠️")
 	}
 
 	if rowsAffected == 0 {
+		tx.Rollback()
 		return utils.ErrorHandler(err,"ERROR retrieving deleted-teacher ⚠️")
 	}
+	if err := addOutboxEvent(tx, events.New(events.TeacherDeleted, id, events.Deleted{ID: id})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
 	return nil
 }
 
@@ -452,6 +513,10 @@ func DeleteTeachersDbHandler(ids []int) ([]int, error) {
 			tx.Rollback()
 			return nil, utils.ErrorHandler(err,fmt.Sprintf("ID %d does not exist ⚠️", id))
 		}
+		if err := addOutboxEvent(tx, events.New(events.TeacherDeleted, id, events.Deleted{ID: id})); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
 
 	// Commit
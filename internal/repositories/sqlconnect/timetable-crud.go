@@ -0,0 +1,340 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+// ClashError - the slot collides with already booked slot(s), reported as 409
+type ClashError struct {
+	Clashes []models.TimetableClash
+}
+
+func (e *ClashError) Error() string {
+	messages := make([]string, len(e.Clashes))
+	for i, c := range e.Clashes {
+		messages[i] = c.Message
+	}
+	return "timetable clash: " + strings.Join(messages, "; ")
+}
+
+// ErrSlotNotFound - reported as 404
+var ErrSlotNotFound = errors.New("Timetable slot Not Found ⚠️")
+
+// ErrSlotTaken - a concurrent request booked the teacher, class or room first (a UNIQUE key fired
+// after the clash check), reported as 409
+var ErrSlotTaken = errors.New("timetable clash: the teacher, class or room was booked for that period in the meantime ⚠️")
+
+// slotWriteError maps the constraint errors of an INSERT/UPDATE on timetable_slots
+func slotWriteError(err error, msg string) error {
+	switch {
+	case isMySQLError(err, errDuplicateEntry):
+		return ErrSlotTaken
+	case isMySQLError(err, errNoReferencedRow):
+		return &ValidationError{fmt.Errorf("unknown period, class, subject or teacher ⚠️")}
+	}
+	return utils.ErrorHandler(err, msg)
+}
+
+// query-param -> db-column
+var timetableFilterParams = map[string]string{
+	"day":        "ts.day",
+	"period":     "ts.period",
+	"class_id":   "ts.class_id",
+	"subject_id": "ts.subject_id",
+	"teacher_id": "ts.teacher_id",
+	"room":       "ts.room",
+}
+
+const slotSelect = `SELECT ts.id, ts.day, ts.period, ts.class_id, ts.subject_id, ts.teacher_id, ts.room,
+	c.name, su.name, CONCAT(t.first_name, ' ', t.last_name), p.start_time, p.end_time
+	FROM timetable_slots ts
+	JOIN classes c ON c.id = ts.class_id
+	JOIN subjects su ON su.id = ts.subject_id
+	JOIN teachers t ON t.id = ts.teacher_id
+	JOIN periods p ON p.period = ts.period`
+
+var dayNames = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+func ValidateTimetableSlot(slot models.TimetableSlot) error {
+	if slot.Day < 1 || slot.Day > 7 {
+		return fmt.Errorf("day must be 1 (Monday) to 7 (Sunday) ⚠️")
+	}
+	if slot.Period < 1 {
+		return fmt.Errorf("period must be positive ⚠️")
+	}
+	if slot.ClassID < 1 || slot.SubjectID < 1 || slot.TeacherID < 1 {
+		return fmt.Errorf("class_id, subject_id and teacher_id are required ⚠️")
+	}
+	if slot.Room == "" {
+		return fmt.Errorf("room is required ⚠️")
+	}
+	return nil
+}
+
+func querySlots(db queryer, qry string, args ...any) ([]models.TimetableSlot, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving timetable! ⚠️")
+	}
+	defer rows.Close()
+
+	slots := []models.TimetableSlot{}
+	for rows.Next() {
+		var s models.TimetableSlot
+		err := rows.Scan(&s.ID, &s.Day, &s.Period, &s.ClassID, &s.SubjectID, &s.TeacherID, &s.Room,
+			&s.Class, &s.Subject, &s.Teacher, &s.StartTime, &s.EndTime)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		slots = append(slots, s)
+	}
+	return slots, nil
+}
+
+// findClashes lists every booked slot (other than the slot itself) that shares
+// the day+period with the same teacher, class or room.
+func findClashes(db queryer, slot models.TimetableSlot) ([]models.TimetableClash, error) {
+	booked, err := querySlots(db, slotSelect+`
+	WHERE ts.day = ? AND ts.period = ? AND ts.id <> ?
+	AND (ts.teacher_id = ? OR ts.class_id = ? OR ts.room = ?)`,
+		slot.Day, slot.Period, slot.ID, slot.TeacherID, slot.ClassID, slot.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	var clashes []models.TimetableClash
+	for _, b := range booked {
+		when := fmt.Sprintf("%s period %d", dayNames[b.Day], b.Period)
+		if b.TeacherID == slot.TeacherID {
+			clashes = append(clashes, models.TimetableClash{Reason: "teacher", Slot: b,
+				Message: fmt.Sprintf("teacher %s is already teaching %s in room %s on %s", b.Teacher, b.Class, b.Room, when)})
+		}
+		if b.ClassID == slot.ClassID {
+			clashes = append(clashes, models.TimetableClash{Reason: "class", Slot: b,
+				Message: fmt.Sprintf("class %s already has %s on %s", b.Class, b.Subject, when)})
+		}
+		if b.Room == slot.Room {
+			clashes = append(clashes, models.TimetableClash{Reason: "room", Slot: b,
+				Message: fmt.Sprintf("room %s is already booked by %s on %s", b.Room, b.Class, when)})
+		}
+	}
+	return clashes, nil
+}
+
+//! GET timetable DB ops. - ?day=&period=&class_id=&teacher_id=&subject_id=&room=
+func GetTimetableDbHandler(r *http.Request) ([]models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry := slotSelect + " WHERE 1=1"
+	var args []any
+	qry, args = AddFiltersFor(r, qry, args, timetableFilterParams)
+	qry += " ORDER BY ts.day, ts.period, c.name"
+	qry, args = AddPagination(r, qry, args)
+
+	return querySlots(db, qry, args...)
+}
+
+//! GET single timetable slot DB ops.
+func GetTimetableSlotDbHandler(id int) (models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	return getSlot(db, id)
+}
+
+func getSlot(db queryer, id int) (models.TimetableSlot, error) {
+	slots, err := querySlots(db, slotSelect+" WHERE ts.id = ?", id)
+	if err != nil {
+		return models.TimetableSlot{}, err
+	}
+	if len(slots) == 0 {
+		return models.TimetableSlot{}, ErrSlotNotFound
+	}
+	return slots[0], nil
+}
+
+//! Add / POST timetable slots DB ops. - all or nothing, slots in the same batch are checked against each other too
+func AddTimetableSlotsDbHandler(newSlots []models.TimetableSlot) ([]models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	added := make([]models.TimetableSlot, len(newSlots))
+	for i, slot := range newSlots {
+		clashes, err := findClashes(tx, slot)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if len(clashes) > 0 {
+			tx.Rollback()
+			return nil, &ClashError{Clashes: clashes}
+		}
+
+		res, err := tx.Exec("INSERT INTO timetable_slots (day, period, class_id, subject_id, teacher_id, room) VALUES (?, ?, ?, ?, ?, ?)",
+			slot.Day, slot.Period, slot.ClassID, slot.SubjectID, slot.TeacherID, slot.Room)
+		if err != nil {
+			tx.Rollback()
+			return nil, slotWriteError(err, "ERROR inserting slot ⚠️")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		added[i], err = getSlot(tx, int(lastId))
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return added, nil
+}
+
+// saveSlot re-checks clashes and writes the slot, inside one transaction
+func saveSlot(db *sql.DB, slot models.TimetableSlot) (models.TimetableSlot, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+
+	clashes, err := findClashes(tx, slot)
+	if err != nil {
+		tx.Rollback()
+		return models.TimetableSlot{}, err
+	}
+	if len(clashes) > 0 {
+		tx.Rollback()
+		return models.TimetableSlot{}, &ClashError{Clashes: clashes}
+	}
+
+	_, err = tx.Exec("UPDATE timetable_slots SET day = ?, period = ?, class_id = ?, subject_id = ?, teacher_id = ?, room = ? WHERE id = ?",
+		slot.Day, slot.Period, slot.ClassID, slot.SubjectID, slot.TeacherID, slot.Room, slot.ID)
+	if err != nil {
+		tx.Rollback()
+		return models.TimetableSlot{}, slotWriteError(err, "ERROR updating slot ⚠️")
+	}
+
+	saved, err := getSlot(tx, slot.ID)
+	if err != nil {
+		tx.Rollback()
+		return models.TimetableSlot{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return saved, nil
+}
+
+//! Update/PUT timetable slot Db ops.
+func UpdateTimetableSlotDbHandler(id int, slot models.TimetableSlot) (models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "timetable_slots", id); err == sql.ErrNoRows {
+		return models.TimetableSlot{}, ErrSlotNotFound
+	} else if err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	slot.ID = id
+	return saveSlot(db, slot)
+}
+
+//! PATCH timetable slot Db ops.
+func PatchTimetableSlotDbHandler(id int, updates map[string]any) (models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.TimetableSlot{}, utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	slot, err := getSlot(db, id)
+	if err != nil {
+		return models.TimetableSlot{}, err
+	}
+	if err := ApplyUpdates(&slot, updates); err != nil {
+		return models.TimetableSlot{}, &ValidationError{Err: err}
+	}
+	if err := ValidateTimetableSlot(slot); err != nil {
+		return models.TimetableSlot{}, &ValidationError{Err: err}
+	}
+	return saveSlot(db, slot)
+}
+
+//! Delete timetable slot Db ops.
+func DeleteTimetableSlotDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DB ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM timetable_slots WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting slot ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting slot ⚠️")
+	}
+	if rowsAffected == 0 {
+		return ErrSlotNotFound
+	}
+	return nil
+}
+
+//! GET weekly timetable of a teacher DB ops.
+func GetTeacherTimetableDbHandler(teacherId int) ([]models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "teachers", teacherId); err != nil {
+		return nil, utils.ErrorHandler(err, "Teacher Not Found! ⚠️")
+	}
+	return querySlots(db, slotSelect+" WHERE ts.teacher_id = ? ORDER BY ts.day, ts.period", teacherId)
+}
+
+//! GET weekly timetable of a class DB ops.
+func GetClassTimetableDbHandler(classId int) ([]models.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := classExists(db, classId); err != nil {
+		return nil, err
+	}
+	return querySlots(db, slotSelect+" WHERE ts.class_id = ? ORDER BY ts.day, ts.period", classId)
+}
@@ -0,0 +1,496 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/pkg/utils"
+)
+
+const webhookColumns = "id, url, events, secret, description, active, created_at"
+
+const deliveryColumns = `id, webhook_id, event_id, event, aggregate_type, aggregate_id, payload, status, attempts, response_status,
+	error, duration_ms, created_at, last_attempt_at`
+
+// reported as 404
+var (
+	ErrWebhookNotFound  = errors.New("Webhook Not Found! ⚠️")
+	ErrDeliveryNotFound = errors.New("Delivery Not Found! ⚠️")
+)
+
+// query-param -> db-column
+var deliveryFilterParams = map[string]string{
+	"status": "status",
+	"event":  "event",
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (models.Webhook, error) {
+	var w models.Webhook
+	var events string
+	var active bool
+	if err := row.Scan(&w.ID, &w.URL, &events, &w.Secret, &w.Description, &active, &w.CreatedAt); err != nil {
+		return models.Webhook{}, err
+	}
+	w.Events = strings.Split(events, ",")
+	w.Active = &active
+	return w, nil
+}
+
+func scanDelivery(row interface{ Scan(...any) error }) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	var responseStatus, durationMS sql.NullInt64
+	var errMsg, lastAttemptAt sql.NullString
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.AggregateType, &d.AggregateID, &payload, &d.Status, &d.Attempts, &responseStatus,
+		&errMsg, &durationMS, &d.CreatedAt, &lastAttemptAt)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	d.Payload = []byte(payload)
+	d.ResponseStatus, d.DurationMS = int(responseStatus.Int64), int(durationMS.Int64)
+	d.Error, d.LastAttemptAt = errMsg.String, lastAttemptAt.String
+	return d, nil
+}
+
+func queryWebhooks(db queryer, qry string, args ...any) ([]models.Webhook, error) {
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving webhooks! ⚠️")
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+//! GET All webhooks DB ops.
+func GetWebhooksDbHandler(r *http.Request) ([]models.Webhook, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	qry, args := AddPagination(r, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id", nil)
+	return queryWebhooks(db, qry, args...)
+}
+
+//! GET single webhook DB ops.
+func GetWebhookDbHandler(id int) (models.Webhook, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	w, err := scanWebhook(db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Webhook{}, ErrWebhookNotFound
+	} else if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return w, nil
+}
+
+//! GET active webhooks subscribed to an event DB ops.
+func GetWebhooksForEventDbHandler(eventType string) ([]models.Webhook, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	// the patterns are matched here rather than in SQL, there are only ever a handful of webhooks
+	active, err := queryWebhooks(db, "SELECT "+webhookColumns+" FROM webhooks WHERE active ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	subscribed := []models.Webhook{}
+	for _, w := range active {
+		if w.Wants(eventType) {
+			subscribed = append(subscribed, w)
+		}
+	}
+	return subscribed, nil
+}
+
+//! Add / POST webhook DB ops.
+func AddWebhookDbHandler(w models.Webhook) (models.Webhook, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	active := w.Active == nil || *w.Active
+	res, err := db.Exec("INSERT INTO webhooks (url, events, secret, description, active) VALUES (?, ?, ?, ?, ?)",
+		w.URL, strings.Join(w.Events, ","), w.Secret, w.Description, active)
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+	}
+
+	added, err := scanWebhook(db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", lastId))
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return added, nil
+}
+
+//! PATCH webhook DB ops. - empty fields of patch are left as they are
+func PatchWebhookDbHandler(id int, patch models.Webhook) (models.Webhook, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	w, err := scanWebhook(db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return models.Webhook{}, ErrWebhookNotFound
+	} else if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	if patch.URL != "" {
+		w.URL = patch.URL
+	}
+	if len(patch.Events) > 0 {
+		w.Events = patch.Events
+	}
+	if patch.Secret != "" {
+		w.Secret = patch.Secret
+	}
+	if patch.Description != "" {
+		w.Description = patch.Description
+	}
+	if patch.Active != nil {
+		w.Active = patch.Active
+	}
+
+	_, err = db.Exec("UPDATE webhooks SET url = ?, events = ?, secret = ?, description = ?, active = ? WHERE id = ?",
+		w.URL, strings.Join(w.Events, ","), w.Secret, w.Description, *w.Active, id)
+	if err != nil {
+		return models.Webhook{}, utils.ErrorHandler(err, "ERROR updating webhook ⚠️")
+	}
+	return w, nil
+}
+
+//! DELETE webhook DB ops. - its delivery log goes with it
+func DeleteWebhookDbHandler(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting webhook ⚠️")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR deleting webhook ⚠️")
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeliveryJob - the job that sends a delivery: its type and retry budget. Deliveries queue their job
+// in their own transaction, so there's never a pending delivery without one.
+type DeliveryJob struct {
+	Type        string
+	MaxAttempts int
+}
+
+// ErrDeliveryUnfinished - a delivery that's still pending/waiting can't be redelivered
+var ErrDeliveryUnfinished = errors.New("delivery hasn't finished yet ⚠️")
+
+// queueDelivery queues the job of a pending delivery and keeps its id on the delivery (see the sweep)
+func queueDelivery(tx execQueryer, job DeliveryJob, webhookId, id int) (int64, error) {
+	payload, err := json.Marshal(models.WebhookDeliveryJob{DeliveryID: id, WebhookID: webhookId})
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR encoding job ⚠️")
+	}
+	jobId, err := addJob(tx, job.Type, payload, job.MaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE webhook_deliveries SET job_id = ? WHERE id = ?", jobId, id); err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR updating delivery ⚠️")
+	}
+	return jobId, nil
+}
+
+// startNextDelivery - delivery id finished, the oldest waiting one of its aggregate becomes pending.
+// Not while another one is pending: a finished redelivery doesn't start the queue a second time.
+func startNextDelivery(tx execQueryer, job DeliveryJob, id int) error {
+	var next, webhookId int
+	err := tx.QueryRow(`SELECT n.id, n.webhook_id FROM webhook_deliveries d
+		JOIN webhook_deliveries n ON n.webhook_id = d.webhook_id AND n.aggregate_type = d.aggregate_type
+			AND n.aggregate_id = d.aggregate_id AND n.status = 'waiting'
+		WHERE d.id = ? AND NOT EXISTS (SELECT 1 FROM webhook_deliveries p WHERE p.webhook_id = d.webhook_id
+			AND p.aggregate_type = d.aggregate_type AND p.aggregate_id = d.aggregate_id AND p.status = 'pending')
+		ORDER BY n.id LIMIT 1 FOR UPDATE`, id).Scan(&next, &webhookId)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if _, err := tx.Exec("UPDATE webhook_deliveries SET status = 'pending' WHERE id = ?", next); err != nil {
+		return utils.ErrorHandler(err, "ERROR updating delivery ⚠️")
+	}
+	_, err = queueDelivery(tx, job, webhookId, next)
+	return err
+}
+
+//! Add a delivery DB ops. - pending (with its job queued), or waiting while an earlier one of the same aggregate
+// (to the same webhook) hasn't finished yet, so a receiver never gets teacher.updated before teacher.created.
+// The outbox may send an event twice - a webhook keeps the delivery it already has of it.
+func AddWebhookDeliveryDbHandler(webhookId int, eventId, eventType, aggregateType string, aggregateId int, payload []byte, job DeliveryJob) (models.WebhookDelivery, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE - a delivery finishing right now either commits first (and isn't counted)
+	// or waits for us (and then finds this one to start next)
+	var unfinished int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries
+		WHERE webhook_id = ? AND aggregate_type = ? AND aggregate_id = ? AND status IN ('waiting', 'pending') FOR UPDATE`,
+		webhookId, aggregateType, aggregateId).Scan(&unfinished)
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	status := models.DeliveryPending
+	if unfinished > 0 {
+		status = models.DeliveryWaiting
+	}
+
+	// IGNORE - uq_webhook_deliveries_event: the event was sent to this webhook before
+	res, err := tx.Exec(`INSERT IGNORE INTO webhook_deliveries (webhook_id, event_id, event, aggregate_type, aggregate_id, payload, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, webhookId, eventId, eventType, aggregateType, aggregateId, string(payload), status)
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR inserting DATA into DB⚠️")
+	}
+	if added == 1 && status == models.DeliveryPending {
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR getting last-inserted-id⚠️")
+		}
+		if _, err := queueDelivery(tx, job, webhookId, int(lastId)); err != nil {
+			return models.WebhookDelivery{}, err
+		}
+	}
+
+	// not there at all: IGNORE dropped the row because the webhook is gone
+	d, err := scanDelivery(tx.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? AND event_id = ?", webhookId, eventId))
+	if err == sql.ErrNoRows {
+		return models.WebhookDelivery{}, ErrWebhookNotFound
+	} else if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if err := tx.Commit(); err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return d, nil
+}
+
+//! GET the delivery log of a webhook DB ops. - ?status=&event=, newest first
+func GetWebhookDeliveriesDbHandler(webhookId int, r *http.Request) ([]models.WebhookDelivery, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	if err := rowExists(db, "webhooks", webhookId); err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+
+	qry := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE webhook_id = ?"
+	args := []any{webhookId}
+	qry, args = AddFiltersFor(r, qry, args, deliveryFilterParams)
+	qry += " ORDER BY id DESC"
+	qry, args = AddPagination(r, qry, args)
+
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "DATABASE-QUERY Error - ERR. retrieving deliveries! ⚠️")
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+//! GET single delivery DB ops.
+func GetWebhookDeliveryDbHandler(webhookId, id int) (models.WebhookDelivery, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	d, err := scanDelivery(db.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ? AND webhook_id = ?", id, webhookId))
+	if err == sql.ErrNoRows {
+		return models.WebhookDelivery{}, ErrDeliveryNotFound
+	} else if err != nil {
+		return models.WebhookDelivery{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	return d, nil
+}
+
+//! RECORD a delivery attempt DB ops. - status stays pending while there are retries left.
+// Once it's succeeded/failed the next waiting delivery of the aggregate is started, in the same transaction.
+func RecordWebhookAttemptDbHandler(id int, status string, responseStatus int, errMsg string, durationMS int, job DeliveryJob) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, response_status = NULLIF(?, 0),
+		error = NULLIF(?, ''), duration_ms = ?, last_attempt_at = NOW() WHERE id = ?`,
+		status, responseStatus, errMsg, durationMS, id)
+	if err != nil {
+		return utils.ErrorHandler(err, "ERROR updating delivery ⚠️")
+	}
+	if status == models.DeliverySucceeded || status == models.DeliveryFailed {
+		if err := startNextDelivery(tx, job, id); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return nil
+}
+
+//! RESET a delivery for a manual redelivery DB ops. - only a finished one, it's pending again and its job is queued
+func ResetWebhookDeliveryDbHandler(id int, job DeliveryJob) (models.Job, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR connecting to DATABASE ⚠️")
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	defer tx.Rollback()
+
+	var webhookId int
+	var status string
+	err = tx.QueryRow("SELECT webhook_id, status FROM webhook_deliveries WHERE id = ? FOR UPDATE", id).Scan(&webhookId, &status)
+	if err == sql.ErrNoRows {
+		return models.Job{}, ErrDeliveryNotFound
+	} else if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	// a pending one has a job already - a second one would send it twice at once
+	if status != models.DeliverySucceeded && status != models.DeliveryFailed {
+		return models.Job{}, ErrDeliveryUnfinished
+	}
+
+	if _, err := tx.Exec("UPDATE webhook_deliveries SET status = 'pending' WHERE id = ?", id); err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR updating delivery ⚠️")
+	}
+	jobId, err := queueDelivery(tx, job, webhookId, id)
+	if err != nil {
+		return models.Job{}, err
+	}
+	queued, err := scanJob(tx.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", jobId))
+	if err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Job{}, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return queued, nil
+}
+
+//! SWEEP deliveries whose job ended without recording a result DB ops. - returns how many were failed
+// (the stale-job sweep gave up on a dead worker, the job was cancelled, recording the attempt failed):
+// they're failed, so the deliveries waiting behind them get their turn.
+func SweepWebhookDeliveriesDbHandler(db *sql.DB, job DeliveryJob) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR starting transaction! ⚠️")
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT d.id FROM webhook_deliveries d LEFT JOIN jobs j ON j.id = d.job_id
+		WHERE d.status = 'pending' AND (j.id IS NULL OR j.status IN ('succeeded', 'failed', 'cancelled'))
+		ORDER BY d.id FOR UPDATE`)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "DB Query Error! ⚠️")
+	}
+	var stuck []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, utils.ErrorHandler(err, "ERROR scanning DB-results! ⚠️")
+		}
+		stuck = append(stuck, id)
+	}
+	rows.Close()
+
+	for _, id := range stuck {
+		_, err := tx.Exec("UPDATE webhook_deliveries SET status = 'failed', error = 'the delivery job ended without a result' WHERE id = ?", id)
+		if err != nil {
+			return 0, utils.ErrorHandler(err, "ERROR updating delivery ⚠️")
+		}
+		if err := startNextDelivery(tx, job, id); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, utils.ErrorHandler(err, "ERROR committing transaction! ⚠️")
+	}
+	return len(stuck), nil
+}
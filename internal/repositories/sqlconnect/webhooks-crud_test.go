@@ -0,0 +1,148 @@
+package sqlconnect
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/iamskyy111/go-rest-api/internal/models"
+)
+
+// These run the SQL against a real MariaDB: SQLCONNECT_TEST_DB names a scratch database with schema/
+// applied, DB_USER/DB_PASSWORD/HOST/DB_PORT point at the server (as for the API). Skipped without it.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	name := os.Getenv("SQLCONNECT_TEST_DB")
+	if name == "" {
+		t.Skip("SQLCONNECT_TEST_DB not set - needs a MariaDB with schema/ applied")
+	}
+	t.Setenv("DB_NAME", name)
+	db, err := ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+const testDeliveryJob = "test.webhooks.deliver"
+
+func TestWebhookDeliveryQueue(t *testing.T) {
+	db := testDB(t)
+	res, err := db.Exec("INSERT INTO webhooks (url, events, secret) VALUES ('https://hooks.example.com/test', '*', 'whsec_0123456789abcdef')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+	webhookId := int(id)
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM webhooks WHERE id = ?", webhookId) // the deliveries go with it
+		db.Exec("DELETE FROM jobs WHERE type = ?", testDeliveryJob)
+	})
+
+	job := DeliveryJob{Type: testDeliveryJob, MaxAttempts: 2}
+	add := func(eventId string, aggregateId int) models.WebhookDelivery {
+		t.Helper()
+		d, err := AddWebhookDeliveryDbHandler(webhookId, eventId, "teacher.updated", "teacher", aggregateId, []byte(`{}`), job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	// state - status and job of a delivery
+	state := func(id int) (string, int) {
+		t.Helper()
+		var status string
+		var jobId sql.NullInt64
+		if err := db.QueryRow("SELECT status, job_id FROM webhook_deliveries WHERE id = ?", id).Scan(&status, &jobId); err != nil {
+			t.Fatal(err)
+		}
+		return status, int(jobId.Int64)
+	}
+	queuedFor := func(jobId int) int {
+		t.Helper()
+		var payload string
+		if err := db.QueryRow("SELECT payload FROM jobs WHERE id = ? AND type = ? AND status = 'queued'", jobId, testDeliveryJob).Scan(&payload); err != nil {
+			t.Fatalf("job %d: %v", jobId, err)
+		}
+		var p models.WebhookDeliveryJob
+		json.Unmarshal([]byte(payload), &p)
+		return p.DeliveryID
+	}
+	countJobs := func() int {
+		t.Helper()
+		var n int
+		db.QueryRow("SELECT COUNT(*) FROM jobs WHERE type = ?", testDeliveryJob).Scan(&n)
+		return n
+	}
+
+	d1 := add("test-evt-1", 7)
+	d2 := add("test-evt-2", 7) // waits for d1
+	d3 := add("test-evt-3", 8) // another teacher
+	if d1.Status != models.DeliveryPending || d2.Status != models.DeliveryWaiting || d3.Status != models.DeliveryPending {
+		t.Fatalf("statuses %s/%s/%s, want pending/waiting/pending", d1.Status, d2.Status, d3.Status)
+	}
+	if _, jobId := state(d1.ID); queuedFor(jobId) != d1.ID {
+		t.Fatal("no job queued for delivery 1")
+	}
+	if _, jobId := state(d2.ID); jobId != 0 {
+		t.Fatal("a job queued for the waiting delivery 2")
+	}
+
+	// the outbox sending evt-1 again changes nothing
+	if again := add("test-evt-1", 7); again.ID != d1.ID || countJobs() != 2 {
+		t.Fatalf("re-sent event: delivery %d, %d jobs - want delivery %d, 2 jobs", again.ID, countJobs(), d1.ID)
+	}
+
+	// a retry keeps d2 waiting, a pending delivery can't be redelivered
+	if err := RecordWebhookAttemptDbHandler(d1.ID, models.DeliveryPending, 500, "receiver answered 500", 12, job); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := state(d2.ID); status != models.DeliveryWaiting {
+		t.Fatalf("delivery 2 is %s while 1 is retried, want waiting", status)
+	}
+	if _, err := ResetWebhookDeliveryDbHandler(d1.ID, job); !errors.Is(err, ErrDeliveryUnfinished) {
+		t.Fatalf("reset of a pending delivery: err = %v, want ErrDeliveryUnfinished", err)
+	}
+
+	// d1 done - d2 goes next, with its job
+	if err := RecordWebhookAttemptDbHandler(d1.ID, models.DeliverySucceeded, 200, "", 12, job); err != nil {
+		t.Fatal(err)
+	}
+	status, d2Job := state(d2.ID)
+	if status != models.DeliveryPending || queuedFor(d2Job) != d2.ID {
+		t.Fatalf("after delivery 1: delivery 2 is %s (job %d), want pending with a queued job", status, d2Job)
+	}
+
+	// the job runner gives up on d2's job (dead worker) - the sweep fails d2, d3's queued job is left alone
+	d4 := add("test-evt-4", 7) // waits for d2
+	if _, err := db.Exec("UPDATE jobs SET status = 'failed' WHERE id = ?", d2Job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SweepWebhookDeliveriesDbHandler(db, job); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := state(d2.ID); status != models.DeliveryFailed {
+		t.Fatalf("after the sweep delivery 2 is %s, want failed", status)
+	}
+	if status, _ := state(d3.ID); status != models.DeliveryPending {
+		t.Fatalf("after the sweep delivery 3 is %s, want pending", status)
+	}
+	if status, d4Job := state(d4.ID); status != models.DeliveryPending || queuedFor(d4Job) != d4.ID {
+		t.Fatalf("after the sweep delivery 4 is %s, want pending with a queued job", status)
+	}
+
+	// a finished delivery can be redelivered
+	redelivery, err := ResetWebhookDeliveryDbHandler(d1.ID, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, jobId := state(d1.ID); status != models.DeliveryPending || jobId != redelivery.ID || queuedFor(jobId) != d1.ID {
+		t.Fatalf("after the redelivery delivery 1 is %s (job %d), want pending with job %d", status, jobId, redelivery.ID)
+	}
+}
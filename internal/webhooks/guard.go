@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Webhook URLs come from API clients, and the server posts to them from inside our network - without a guard
+// anybody allowed to add a webhook could make it call internal services (SSRF). So the address is checked
+// when the URL is added and again, after DNS, for every connection: a name that resolves to 10.0.0.5 at
+// delivery time gets nowhere either (and redirects aren't followed at all).
+// WEBHOOK_ALLOW_PRIVATE_TARGETS=true turns it off for local development.
+
+// ErrBlockedTarget - the webhook URL points into a private/loopback/link-local network
+var ErrBlockedTarget = errors.New("webhook target is a private, loopback or link-local address ⚠️")
+
+// ranges netip has no Is... for
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+func allowPrivateTargets() bool {
+	return os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS") == "true"
+}
+
+func checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if allowPrivateTargets() {
+		return nil
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return ErrBlockedTarget
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return ErrBlockedTarget
+		}
+	}
+	return nil
+}
+
+// ValidateURL - an absolute http(s) URL that doesn't name a private address or localhost.
+// Names are resolved at delivery time, where the dialer checks the address they resolve to.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http(s) URL ⚠️")
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkAddr(addr)
+	}
+	if !allowPrivateTargets() && (strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost")) {
+		return ErrBlockedTarget
+	}
+	return nil
+}
+
+// guardedDialer refuses connections to blocked addresses - Control runs after DNS, with the IP it connects to
+var guardedDialer = &net.Dialer{
+	Timeout: deliveryTimeout,
+	Control: func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return err
+		}
+		return checkAddr(addr)
+	},
+}
+
+var client = &http.Client{
+	Timeout: deliveryTimeout,
+	Transport: &http.Transport{
+		Proxy:               nil, // a proxy would do the dialing - and the checking - for us
+		DialContext:         guardedDialer.DialContext,
+		TLSHandshakeTimeout: deliveryTimeout,
+		MaxIdleConnsPerHost: 4,
+	},
+	// a receiver answers, it doesn't send us elsewhere
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// Store keeps the webhooks and their delivery log - the database; the tests use one in memory.
+// It queues the delivery jobs as well, together with the deliveries they're for.
+type Store interface {
+	WebhooksFor(eventType string) ([]models.Webhook, error)
+	Webhook(id int) (models.Webhook, error)
+	Delivery(webhookId, id int) (models.WebhookDelivery, error)
+	// AddDelivery stores a delivery of e and queues its job - unless it waits behind an earlier delivery
+	// of the aggregate, or the webhook already has one of e (the outbox sent it again): that one is returned
+	AddDelivery(webhookId int, e events.Event, payload []byte) (models.WebhookDelivery, error)
+	// RecordAttempt - once the delivery succeeded/failed, the next waiting one of its aggregate is queued
+	RecordAttempt(id int, status string, responseStatus int, errMsg string, durationMS int) error
+	// ResetDelivery queues a finished delivery again (sqlconnect.ErrDeliveryUnfinished otherwise)
+	ResetDelivery(id int) (models.Job, error)
+}
+
+var store Store = sqlStore{}
+
+// deliveryJob - JobDeliver with WEBHOOK_MAX_ATTEMPTS
+func deliveryJob() sqlconnect.DeliveryJob {
+	return sqlconnect.DeliveryJob{Type: JobDeliver, MaxAttempts: maxAttempts()}
+}
+
+type sqlStore struct{}
+
+func (sqlStore) WebhooksFor(eventType string) ([]models.Webhook, error) {
+	return sqlconnect.GetWebhooksForEventDbHandler(eventType)
+}
+
+func (sqlStore) Webhook(id int) (models.Webhook, error) {
+	return sqlconnect.GetWebhookDbHandler(id)
+}
+
+func (sqlStore) Delivery(webhookId, id int) (models.WebhookDelivery, error) {
+	return sqlconnect.GetWebhookDeliveryDbHandler(webhookId, id)
+}
+
+func (sqlStore) AddDelivery(webhookId int, e events.Event, payload []byte) (models.WebhookDelivery, error) {
+	return sqlconnect.AddWebhookDeliveryDbHandler(webhookId, e.ID, e.Type, e.AggregateType, e.AggregateID, payload, deliveryJob())
+}
+
+func (sqlStore) RecordAttempt(id int, status string, responseStatus int, errMsg string, durationMS int) error {
+	return sqlconnect.RecordWebhookAttemptDbHandler(id, status, responseStatus, errMsg, durationMS, deliveryJob())
+}
+
+func (sqlStore) ResetDelivery(id int) (models.Job, error) {
+	return sqlconnect.ResetWebhookDeliveryDbHandler(id, deliveryJob())
+}
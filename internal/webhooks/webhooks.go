@@ -0,0 +1,210 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// Every event a webhook subscribed to is stored as a delivery and sent by a background job,
+// so a slow or dead receiver never holds up the outbox and retries (with backoff) survive restarts.
+//
+// Receivers verify a delivery by computing
+//
+//	hex(HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body))
+//
+// and comparing it with the X-Webhook-Signature header ("sha256=<hex>"). The response body of a
+// receiver is never read into the delivery log - only its status (see guard.go for why).
+//
+// Deliveries of one aggregate (teacher 7, student 3) to a webhook go out in the order of the events:
+// a delivery waits while an earlier one is pending (retries included) and starts once that one
+// succeeded or failed for good. Different aggregates and webhooks don't wait for each other.
+// A manual redelivery is sent right away - it's a repeat of an old event, receivers see that by its id.
+// Nothing may stay pending for good, or everything behind it would wait for good too: the job of a
+// delivery is queued in the delivery's transaction, lookups that fail are retried (and on the last
+// attempt the delivery is failed), and the sweep (Start) fails deliveries whose job ended without a
+// result - cancelled, or given up on by the job runner's stale sweep.
+
+const JobDeliver = "webhooks.deliver"
+
+const deliveryTimeout = 10 * time.Second
+
+// how often the sweep looks for deliveries whose job ended without a result
+const sweepEvery = time.Minute
+
+// Register adds the delivery job - call it before jobs.Start()
+func Register() {
+	jobs.Register(JobDeliver, deliver)
+}
+
+// Start runs the sweep of stuck deliveries until ctx is done - db is the server's long-lived one
+func Start(ctx context.Context, db *sql.DB) {
+	go func() {
+		ticker := time.NewTicker(sweepEvery)
+		defer ticker.Stop()
+		for {
+			if n, err := sqlconnect.SweepWebhookDeliveriesDbHandler(db, deliveryJob()); err != nil {
+				log.Printf("webhooks: sweep: %v", err)
+			} else if n > 0 {
+				log.Printf("webhooks: failed %d deliveries whose job ended without a result", n)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Sink is the outbox sink of the webhooks
+type Sink struct{}
+
+func (Sink) Name() string { return "webhooks" }
+
+// Send creates a delivery for every webhook that wants the event. If it fails half-way the outbox
+// sends the event again - the webhooks that already got a delivery of it keep that one.
+func (Sink) Send(ctx context.Context, e events.Event) error {
+	hooks, err := store.WebhooksFor(e.Type)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if _, err := store.AddDelivery(hook.ID, e, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxAttempts - WEBHOOK_MAX_ATTEMPTS (8 => retries for ~20 minutes)
+func maxAttempts() int {
+	if n, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+		return n
+	}
+	return 8
+}
+
+// Sign returns the X-Webhook-Signature value for body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Redeliver (re)sends a stored delivery - same event id and body, so receivers can spot duplicates
+// - only a finished one (sqlconnect.ErrDeliveryUnfinished otherwise)
+func Redeliver(d models.WebhookDelivery) (models.Job, error) {
+	return store.ResetDelivery(d.ID)
+}
+
+func deliver(ctx context.Context, job models.Job, report func(done, total int)) (any, error) {
+	var p models.WebhookDeliveryJob
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	// a failed lookup (the DB is away for a moment) is retried like a failed post - on the last attempt the
+	// delivery is failed. (A deleted webhook took its deliveries with it, failing those changes nothing.)
+	hook, err := store.Webhook(p.WebhookID)
+	var d models.WebhookDelivery
+	if err == nil {
+		d, err = store.Delivery(p.WebhookID, p.DeliveryID)
+	}
+	if err != nil {
+		if job.Attempts >= job.MaxAttempts {
+			d = models.WebhookDelivery{ID: p.DeliveryID, WebhookID: p.WebhookID}
+			if err := record(d, models.DeliveryFailed, 0, err.Error(), 0); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if hook.Active != nil && !*hook.Active {
+		if err := record(d, models.DeliveryFailed, 0, "webhook is disabled", 0); err != nil {
+			return nil, err
+		}
+		return nil, jobs.Permanent(fmt.Errorf("webhook %d is disabled", hook.ID))
+	}
+
+	status, duration, err := post(ctx, hook, d)
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("receiver answered %d", status)
+	}
+	if err == nil {
+		// not recorded: the job is retried and the receiver gets the delivery again (same id) - at-least-once
+		if err := record(d, models.DeliverySucceeded, status, "", duration); err != nil {
+			return nil, err
+		}
+		return map[string]int{"delivery_id": d.ID, "response_status": status}, nil
+	}
+
+	// the job runner retries with backoff; the delivery only counts as failed once it gives up
+	permanent := errors.Is(err, ErrBlockedTarget)
+	deliveryStatus := models.DeliveryPending
+	if permanent || job.Attempts >= job.MaxAttempts {
+		deliveryStatus = models.DeliveryFailed
+	}
+	if err := record(d, deliveryStatus, status, err.Error(), duration); err != nil {
+		return nil, err
+	}
+	if permanent {
+		return nil, jobs.Permanent(err)
+	}
+	return nil, err
+}
+
+// record logs the attempt (once d is finished the store starts the delivery that waited for it).
+// An error is returned to the job runner: the attempt is retried, or the sweep fails d after the last one.
+func record(d models.WebhookDelivery, status string, responseStatus int, errMsg string, durationMS int) error {
+	if err := store.RecordAttempt(d.ID, status, responseStatus, errMsg, durationMS); err != nil {
+		log.Printf("webhook delivery %d: recording the attempt: %v", d.ID, err)
+		return fmt.Errorf("recording the attempt: %w", err)
+	}
+	return nil
+}
+
+func post(ctx context.Context, hook models.Webhook, d models.WebhookDelivery) (status int, durationMS int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-rest-api-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, d.Payload))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	durationMS = int(time.Since(start).Milliseconds())
+	if err != nil {
+		return 0, durationMS, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+	return resp.StatusCode, durationMS, nil
+}
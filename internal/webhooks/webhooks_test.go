@@ -0,0 +1,493 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/iamskyy111/go-rest-api/internal/events"
+	"github.com/iamskyy111/go-rest-api/internal/jobs"
+	"github.com/iamskyy111/go-rest-api/internal/models"
+	"github.com/iamskyy111/go-rest-api/internal/repositories/sqlconnect"
+)
+
+// memStore - the delivery log and the job queue in memory. failLookup/failRecord make the
+// lookups/RecordAttempt fail, like a DB that's away for a moment.
+type memStore struct {
+	mu         sync.Mutex
+	hooks      []models.Webhook
+	deliveries []models.WebhookDelivery
+	jobs       []models.Job
+	failLookup error
+	failRecord error
+}
+
+func (s *memStore) WebhooksFor(eventType string) ([]models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hooks []models.Webhook
+	for _, hook := range s.hooks {
+		if hook.Wants(eventType) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+func (s *memStore) Webhook(id int) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failLookup != nil {
+		return models.Webhook{}, s.failLookup
+	}
+	for _, hook := range s.hooks {
+		if hook.ID == id {
+			return hook, nil
+		}
+	}
+	return models.Webhook{}, errors.New("webhook not found")
+}
+
+func (s *memStore) Delivery(webhookId, id int) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failLookup != nil {
+		return models.WebhookDelivery{}, s.failLookup
+	}
+	if id < 1 || id > len(s.deliveries) || s.deliveries[id-1].WebhookID != webhookId {
+		return models.WebhookDelivery{}, errors.New("delivery not found")
+	}
+	return s.deliveries[id-1], nil
+}
+
+// queue - the job of delivery d, like the job runner would get it
+func (s *memStore) queue(d models.WebhookDelivery) models.Job {
+	payload, _ := json.Marshal(models.WebhookDeliveryJob{DeliveryID: d.ID, WebhookID: d.WebhookID})
+	job := models.Job{ID: len(s.jobs) + 1, Type: JobDeliver, Payload: payload, MaxAttempts: maxAttempts()}
+	s.jobs = append(s.jobs, job)
+	return job
+}
+
+func sameQueue(a, b models.WebhookDelivery) bool {
+	return a.WebhookID == b.WebhookID && a.AggregateType == b.AggregateType && a.AggregateID == b.AggregateID
+}
+
+func (s *memStore) AddDelivery(webhookId int, e events.Event, payload []byte) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := models.WebhookDelivery{ID: len(s.deliveries) + 1, WebhookID: webhookId, EventID: e.ID, Event: e.Type,
+		AggregateType: e.AggregateType, AggregateID: e.AggregateID, Payload: payload, Status: models.DeliveryPending}
+	for _, earlier := range s.deliveries {
+		if earlier.WebhookID == webhookId && earlier.EventID == e.ID {
+			return earlier, nil
+		}
+		if sameQueue(earlier, d) && (earlier.Status == models.DeliveryPending || earlier.Status == models.DeliveryWaiting) {
+			d.Status = models.DeliveryWaiting
+		}
+	}
+	s.deliveries = append(s.deliveries, d)
+	if d.Status == models.DeliveryPending {
+		s.queue(d)
+	}
+	return d, nil
+}
+
+func (s *memStore) RecordAttempt(id int, status string, responseStatus int, errMsg string, durationMS int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failRecord != nil {
+		return s.failRecord
+	}
+	if id < 1 || id > len(s.deliveries) {
+		return nil // UPDATE ... WHERE id = ? of a deleted delivery
+	}
+	d := &s.deliveries[id-1]
+	d.Status, d.ResponseStatus, d.Error, d.DurationMS = status, responseStatus, errMsg, durationMS
+	d.Attempts++
+	if status == models.DeliveryPending {
+		return nil
+	}
+	for _, other := range s.deliveries {
+		if other.Status == models.DeliveryPending && sameQueue(other, *d) {
+			return nil
+		}
+	}
+	for i := range s.deliveries {
+		if next := &s.deliveries[i]; next.Status == models.DeliveryWaiting && sameQueue(*next, *d) {
+			next.Status = models.DeliveryPending
+			s.queue(*next)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memStore) ResetDelivery(id int) (models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &s.deliveries[id-1]
+	if d.Status != models.DeliverySucceeded && d.Status != models.DeliveryFailed {
+		return models.Job{}, sqlconnect.ErrDeliveryUnfinished
+	}
+	d.Status = models.DeliveryPending
+	return s.queue(*d), nil
+}
+
+// receiver is an httptest receiver answering with the statuses given, one per request (then 200)
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		rc.requests = append(rc.requests, receivedRequest{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(rc.statuses) > 0 {
+			status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+		}
+		rc.mu.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, "internal details the delivery log must not keep")
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest(nil), rc.requests...)
+}
+
+// setup swaps the database (and with it the job queue) for memory; the queued jobs are run by runJob
+func setup(t *testing.T, hooks ...models.Webhook) (*memStore, *[]models.Job) {
+	t.Helper()
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true") // the receiver listens on 127.0.0.1
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+
+	mem := &memStore{hooks: hooks}
+	oldStore := store
+	store = mem
+	t.Cleanup(func() { store = oldStore })
+	return mem, &mem.jobs
+}
+
+// runJob runs attempt number `attempt` of a queued job, like the job runner does
+func runJob(job models.Job, attempt int) error {
+	job.Attempts = attempt
+	_, err := deliver(context.Background(), job, func(done, total int) {})
+	return err
+}
+
+func TestDeliverySignature(t *testing.T) {
+	rc := newReceiver(t)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+
+	if err := (Sink{}).Send(context.Background(), events.New(events.TeacherCreated, 7, map[string]string{"first_name": "Ada"})); err != nil {
+		t.Fatal(err)
+	}
+	if len(*queued) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(*queued))
+	}
+	if err := runJob((*queued)[0], 1); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rc.received()
+	if len(got) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(got))
+	}
+	timestamp, err := strconv.ParseInt(got[0].header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("X-Webhook-Timestamp: %v", err)
+	}
+	if want := Sign("whsec_0123456789abcdef", timestamp, got[0].body); got[0].header.Get("X-Webhook-Signature") != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got[0].header.Get("X-Webhook-Signature"), want)
+	}
+	if Sign("another-secret-entirely", timestamp, got[0].body) == got[0].header.Get("X-Webhook-Signature") {
+		t.Error("signature doesn't depend on the secret")
+	}
+	if got[0].header.Get("X-Webhook-Event") != events.TeacherCreated || got[0].header.Get("X-Webhook-Delivery") != "1" {
+		t.Errorf("event/delivery headers = %q/%q", got[0].header.Get("X-Webhook-Event"), got[0].header.Get("X-Webhook-Delivery"))
+	}
+	if string(got[0].body) != string(mem.deliveries[0].Payload) {
+		t.Errorf("body = %s, want the stored payload %s", got[0].body, mem.deliveries[0].Payload)
+	}
+}
+
+func TestDeliveryRetriesAndLog(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"teacher.*"}, Secret: "whsec_0123456789abcdef"})
+
+	if err := (Sink{}).Send(context.Background(), events.New(events.TeacherUpdated, 7, nil)); err != nil {
+		t.Fatal(err)
+	}
+	job := (*queued)[0]
+
+	// non-2xx: the job fails with a retryable error, the delivery stays pending
+	err := runJob(job, 1)
+	if err == nil || jobs.IsPermanent(err) {
+		t.Fatalf("attempt 1: err = %v, want a retryable error", err)
+	}
+	d := mem.deliveries[0]
+	if d.Status != models.DeliveryPending || d.Attempts != 1 || d.ResponseStatus != http.StatusInternalServerError || d.Error == "" {
+		t.Errorf("log after attempt 1 = %+v", d)
+	}
+
+	if err := runJob(job, 2); err == nil {
+		t.Fatal("attempt 2: want an error for 503")
+	}
+	if err := runJob(job, 3); err != nil {
+		t.Fatalf("attempt 3: %v", err)
+	}
+	d = mem.deliveries[0]
+	if d.Status != models.DeliverySucceeded || d.Attempts != 3 || d.ResponseStatus != http.StatusOK {
+		t.Errorf("log after attempt 3 = %+v", d)
+	}
+	if len(rc.received()) != 3 {
+		t.Errorf("receiver got %d requests, want 3", len(rc.received()))
+	}
+}
+
+func TestDeliveryFailsAfterLastAttempt(t *testing.T) {
+	rc := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+
+	(Sink{}).Send(context.Background(), events.New(events.StudentCreated, 3, nil))
+	job := (*queued)[0]
+	for attempt := 1; attempt <= job.MaxAttempts; attempt++ {
+		if err := runJob(job, attempt); err == nil {
+			t.Fatalf("attempt %d: want an error for 502", attempt)
+		}
+	}
+	if d := mem.deliveries[0]; d.Status != models.DeliveryFailed || d.Attempts != 3 {
+		t.Errorf("log after the last attempt = %+v", d)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "1")
+
+	(Sink{}).Send(context.Background(), events.New(events.StudentDeleted, 3, events.Deleted{ID: 3}))
+	runJob((*queued)[0], 1)
+	if d := mem.deliveries[0]; d.Status != models.DeliveryFailed {
+		t.Fatalf("status = %s, want failed", d.Status)
+	}
+
+	job, err := Redeliver(mem.deliveries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem.deliveries[0].Status != models.DeliveryPending || len(*queued) != 2 {
+		t.Fatalf("after Redeliver: status %s, %d jobs queued", mem.deliveries[0].Status, len(*queued))
+	}
+	if err := runJob(job, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rc.received()
+	if len(got) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(got))
+	}
+	// same delivery, same event - receivers can tell it's a repeat
+	if got[0].header.Get("X-Webhook-Delivery") != got[1].header.Get("X-Webhook-Delivery") || string(got[0].body) != string(got[1].body) {
+		t.Error("redelivery differs from the original delivery")
+	}
+	if d := mem.deliveries[0]; d.Status != models.DeliverySucceeded || d.Attempts != 2 {
+		t.Errorf("log after the redelivery = %+v", d)
+	}
+}
+
+// deliveryOf - the delivery a queued job is for
+func deliveryOf(t *testing.T, job models.Job) int {
+	t.Helper()
+	var p models.WebhookDeliveryJob
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p.DeliveryID
+}
+
+func TestDeliveriesOfAnAggregateKeepTheirOrder(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "2")
+
+	ctx := context.Background()
+	(Sink{}).Send(ctx, events.New(events.TeacherCreated, 7, nil)) // 1
+	(Sink{}).Send(ctx, events.New(events.TeacherUpdated, 7, nil)) // 2 - waits for 1
+	(Sink{}).Send(ctx, events.New(events.TeacherCreated, 8, nil)) // 3 - another teacher, goes right away
+	(Sink{}).Send(ctx, events.New(events.TeacherDeleted, 7, nil)) // 4 - waits for 2
+
+	if len(*queued) != 2 || deliveryOf(t, (*queued)[0]) != 1 || deliveryOf(t, (*queued)[1]) != 3 {
+		t.Fatalf("queued %d jobs, want deliveries 1 and 3", len(*queued))
+	}
+	if mem.deliveries[1].Status != models.DeliveryWaiting || mem.deliveries[3].Status != models.DeliveryWaiting {
+		t.Fatalf("statuses %s/%s, want waiting", mem.deliveries[1].Status, mem.deliveries[3].Status)
+	}
+
+	// a retry of 1 doesn't let 2 overtake it
+	runJob((*queued)[0], 1)
+	if len(*queued) != 2 {
+		t.Fatalf("%d jobs queued while delivery 1 is retried, want 2", len(*queued))
+	}
+	if err := runJob((*queued)[0], 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(*queued) != 3 || deliveryOf(t, (*queued)[2]) != 2 {
+		t.Fatalf("after delivery 1: %d jobs queued, want delivery 2 next", len(*queued))
+	}
+	runJob((*queued)[1], 1)
+	runJob((*queued)[2], 1)
+	if len(*queued) != 4 || deliveryOf(t, (*queued)[3]) != 4 {
+		t.Fatalf("after delivery 2: %d jobs queued, want delivery 4 next", len(*queued))
+	}
+	runJob((*queued)[3], 1)
+
+	var order []string
+	for _, req := range rc.received() {
+		order = append(order, req.header.Get("X-Webhook-Delivery"))
+	}
+	if want := []string{"1", "1", "3", "2", "4"}; !slices.Equal(order, want) {
+		t.Errorf("receiver got deliveries %v, want %v", order, want)
+	}
+}
+
+func TestFailedLookupsAreRetried(t *testing.T) {
+	rc := newReceiver(t)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+
+	ctx := context.Background()
+	(Sink{}).Send(ctx, events.New(events.TeacherCreated, 7, nil)) // 1
+	(Sink{}).Send(ctx, events.New(events.TeacherUpdated, 7, nil)) // 2 - waits for 1
+	mem.failLookup = errors.New("dial tcp: connection refused")
+
+	err := runJob((*queued)[0], 1)
+	if err == nil || jobs.IsPermanent(err) {
+		t.Fatalf("attempt 1: err = %v, want a retryable error", err)
+	}
+	if mem.deliveries[0].Status != models.DeliveryPending || len(*queued) != 1 {
+		t.Fatalf("after attempt 1: status %s, %d jobs queued", mem.deliveries[0].Status, len(*queued))
+	}
+
+	// the last attempt fails the delivery - and the one behind it gets its turn
+	if err := runJob((*queued)[0], 3); err == nil {
+		t.Fatal("attempt 3: want an error")
+	}
+	if d := mem.deliveries[0]; d.Status != models.DeliveryFailed || d.Error == "" {
+		t.Fatalf("log after the last attempt = %+v", d)
+	}
+	if len(*queued) != 2 || deliveryOf(t, (*queued)[1]) != 2 {
+		t.Fatalf("%d jobs queued, want delivery 2 next", len(*queued))
+	}
+
+	mem.failLookup = nil
+	if err := runJob((*queued)[1], 1); err != nil {
+		t.Fatal(err)
+	}
+	if mem.deliveries[1].Status != models.DeliverySucceeded {
+		t.Errorf("status of delivery 2 = %s, want succeeded", mem.deliveries[1].Status)
+	}
+}
+
+func TestUnrecordedAttemptIsRetried(t *testing.T) {
+	rc := newReceiver(t)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+
+	(Sink{}).Send(context.Background(), events.New(events.StudentCreated, 3, nil))
+	mem.failRecord = errors.New("dial tcp: connection refused")
+	err := runJob((*queued)[0], 1)
+	if err == nil || jobs.IsPermanent(err) {
+		t.Fatalf("err = %v, want a retryable error", err)
+	}
+	if mem.deliveries[0].Status != models.DeliveryPending {
+		t.Fatalf("status = %s, want pending", mem.deliveries[0].Status)
+	}
+
+	// sent again - same delivery id, so the receiver can drop the repeat
+	mem.failRecord = nil
+	if err := runJob((*queued)[0], 2); err != nil {
+		t.Fatal(err)
+	}
+	got := rc.received()
+	if len(got) != 2 || got[0].header.Get("X-Webhook-Delivery") != got[1].header.Get("X-Webhook-Delivery") {
+		t.Fatalf("receiver got %d requests, want the same delivery twice", len(got))
+	}
+	if mem.deliveries[0].Status != models.DeliverySucceeded {
+		t.Errorf("status = %s, want succeeded", mem.deliveries[0].Status)
+	}
+}
+
+func TestResentEventIsDeliveredOnce(t *testing.T) {
+	rc := newReceiver(t)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+
+	// the outbox sends an event again when a sink failed half-way
+	e := events.New(events.TeacherCreated, 7, nil)
+	(Sink{}).Send(context.Background(), e)
+	(Sink{}).Send(context.Background(), e)
+	if len(mem.deliveries) != 1 || len(*queued) != 1 {
+		t.Fatalf("%d deliveries, %d jobs - want 1 of each", len(mem.deliveries), len(*queued))
+	}
+
+	// nor does a redelivery of a pending delivery run a second job next to the first
+	if _, err := Redeliver(mem.deliveries[0]); !errors.Is(err, sqlconnect.ErrDeliveryUnfinished) {
+		t.Fatalf("Redeliver of a pending delivery: err = %v, want ErrDeliveryUnfinished", err)
+	}
+	if len(*queued) != 1 {
+		t.Errorf("%d jobs queued, want 1", len(*queued))
+	}
+}
+
+func TestPrivateTargetsAreBlocked(t *testing.T) {
+	rc := newReceiver(t)
+	mem, queued := setup(t, models.Webhook{ID: 1, URL: rc.URL, Events: []string{"*"}, Secret: "whsec_0123456789abcdef"})
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+
+	(Sink{}).Send(context.Background(), events.New(events.TeacherCreated, 1, nil))
+	err := runJob((*queued)[0], 1)
+	if !errors.Is(err, ErrBlockedTarget) || !jobs.IsPermanent(err) {
+		t.Fatalf("err = %v, want a permanent ErrBlockedTarget", err)
+	}
+	if len(rc.received()) != 0 {
+		t.Error("the loopback receiver was reached")
+	}
+	if mem.deliveries[0].ResponseStatus != 0 {
+		t.Errorf("response status %d recorded for a blocked target", mem.deliveries[0].ResponseStatus)
+	}
+
+	for url, blocked := range map[string]bool{
+		"http://127.0.0.1:8080/hook":        true,
+		"http://localhost/hook":             true,
+		"http://10.1.2.3/hook":              true,
+		"http://169.254.169.254/latest":     true,
+		"http://[::1]/hook":                 true,
+		"http://[::ffff:192.168.0.1]/hook":  true,
+		"https://hooks.example.com/webhook": false,
+		"https://93.184.216.34/hook":        false,
+	} {
+		if err := ValidateURL(url); errors.Is(err, ErrBlockedTarget) != blocked {
+			t.Errorf("ValidateURL(%s) = %v, blocked want %v", url, err, blocked)
+		}
+	}
+}
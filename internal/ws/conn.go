@@ -0,0 +1,297 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// A minimal RFC 6455 server side: the handshake, framing, fragmented messages and the control frames.
+// No extensions (permessage-deflate) are negotiated - attendance messages are tiny.
+
+// Opcodes
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// Close codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseTooBig          = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+const handshakeGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError is returned by ReadMessage once the peer closed the connection (or broke the protocol)
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	ReadLimit    int64         // max. size of a message, bigger ones close the connection with 1009
+	WriteTimeout time.Duration // per frame
+	OnPong       func()        // called for every pong - e.g. to move the read deadline
+
+	wmu        sync.Mutex
+	closeSent  bool
+	closeOnce  sync.Once
+	fragmented []byte
+}
+
+// IsUpgrade - the request asks for a websocket
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade does the opening handshake and takes the connection over from net/http.
+// On error a response has been written already.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		http.Error(w, "websocket upgrade required ⚠️", http.StatusUpgradeRequired)
+		return nil, errors.New("not a websocket request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version ⚠️", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "invalid Sec-WebSocket-Key ⚠️", http.StatusBadRequest)
+		return nil, errors.New("invalid Sec-WebSocket-Key")
+	}
+
+	// http.ResponseController finds the hijacker behind the middleware wrappers (Unwrap)
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket not supported on this connection ⚠️", http.StatusInternalServerError)
+		return nil, err
+	}
+	// the server's read/write timeouts still sit on the connection
+	netConn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + handshakeGUID))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return &Conn{conn: netConn, br: brw.Reader, ReadLimit: 64 << 10, WriteTimeout: 10 * time.Second}, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// ReadMessage returns the next text/binary message. Pings are answered on the way;
+// a close frame is answered as well and comes back as *CloseError.
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	var msgOpcode int
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			var closeErr *CloseError
+			if errors.As(err, &closeErr) && closeErr.Code != CloseNormal {
+				c.WriteClose(closeErr.Code, closeErr.Reason)
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			if c.OnPong != nil {
+				c.OnPong()
+			}
+			continue
+		case OpClose:
+			closeErr := &CloseError{Code: 1005} // no status code in the frame
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.WriteClose(CloseNormal, "")
+			return 0, nil, closeErr
+		case OpText, OpBinary:
+			if c.fragmented != nil {
+				return 0, nil, c.fail(CloseProtocolError, "new message inside a fragmented one")
+			}
+			msgOpcode = op
+			c.fragmented = payload
+		case OpContinuation:
+			if c.fragmented == nil {
+				return 0, nil, c.fail(CloseProtocolError, "continuation without a message")
+			}
+			if int64(len(c.fragmented)+len(payload)) > c.ReadLimit {
+				return 0, nil, c.fail(CloseTooBig, "message too big")
+			}
+			c.fragmented = append(c.fragmented, payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if !fin {
+			continue
+		}
+		data, c.fragmented = c.fragmented, nil
+		if msgOpcode == OpText && !utf8.Valid(data) {
+			return 0, nil, c.fail(CloseInvalidPayload, "text message is not UTF-8")
+		}
+		return msgOpcode, data, nil
+	}
+}
+
+// fail closes the connection with code and returns the matching error
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = int(head[0] & 0x0F)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "client frames must be masked"}
+	}
+
+	length := int64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+	}
+	if opcode >= OpClose && (length > 125 || !fin) {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "invalid control frame"}
+	}
+	if length > c.ReadLimit {
+		return false, 0, nil, &CloseError{Code: CloseTooBig, Reason: "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends data as a single frame; safe to call from several goroutines
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.writeFrame(opcode, data)
+}
+
+func (c *Conn) WritePing() error {
+	return c.writeFrame(OpPing, nil)
+}
+
+// WriteClose starts (or answers) the closing handshake; later frames are dropped
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason[:min(len(reason), 123)]...)
+	err := c.writeFrame(OpClose, payload)
+	c.wmu.Lock()
+	c.closeSent = true
+	c.wmu.Unlock()
+	return err
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+
+	frame := []byte{0x80 | byte(opcode)} // servers send unmasked, unfragmented frames
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close drops the TCP connection (after WriteClose for a clean shutdown)
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() { err = c.conn.Close() })
+	return err
+}
@@ -0,0 +1,138 @@
+package ws
+
+import (
+	"sync"
+	"time"
+)
+
+// Hub groups connections into rooms (e.g. one per class) and fans messages out to them.
+// Every client gets its own send buffer and writer goroutine, so one slow client never holds up
+// the others: when its buffer is full it's disconnected (1013) and has to reconnect and reload.
+
+const (
+	pongWait   = 60 * time.Second // a client that doesn't answer pings for this long is gone
+	pingPeriod = pongWait * 9 / 10
+)
+
+type Hub struct {
+	SendBuffer int // messages queued per client
+
+	mu    sync.RWMutex
+	rooms map[string]map[*Client]bool
+}
+
+func NewHub(sendBuffer int) *Hub {
+	return &Hub{SendBuffer: sendBuffer, rooms: map[string]map[*Client]bool{}}
+}
+
+type Client struct {
+	Conn *Conn
+	Room string
+	Info any // whatever the caller wants to know about the client (e.g. who it is)
+
+	hub      *Hub
+	send     chan []byte
+	kick     chan int // close code
+	kickOnce sync.Once
+	done     chan struct{}
+}
+
+// Join adds conn to room and starts its writer; the caller runs the read loop and calls Leave at the end
+func (h *Hub) Join(room string, conn *Conn, info any) *Client {
+	c := &Client{Conn: conn, Room: room, Info: info, hub: h,
+		send: make(chan []byte, h.SendBuffer), kick: make(chan int, 1), done: make(chan struct{})}
+
+	// the read deadline moves with every pong; no pong -> ReadMessage fails
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.OnPong = func() { conn.SetReadDeadline(time.Now().Add(pongWait)) }
+
+	h.mu.Lock()
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*Client]bool{}
+	}
+	h.rooms[room][c] = true
+	h.mu.Unlock()
+
+	go c.writeLoop()
+	return c
+}
+
+// Leave removes the client and closes its connection
+func (h *Hub) Leave(c *Client) {
+	h.mu.Lock()
+	delete(h.rooms[c.Room], c)
+	if len(h.rooms[c.Room]) == 0 {
+		delete(h.rooms, c.Room)
+	}
+	h.mu.Unlock()
+
+	c.Close(CloseNormal)
+	<-c.done
+	c.Conn.Close()
+}
+
+// Members - Info of every client in room
+func (h *Hub) Members(room string) []any {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	members := make([]any, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		members = append(members, c.Info)
+	}
+	return members
+}
+
+// Broadcast queues msg for every client in room
+func (h *Hub) Broadcast(room string, msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.rooms[room] {
+		c.Send(msg)
+	}
+}
+
+// Send queues msg for the client; a full buffer disconnects it
+func (c *Client) Send(msg []byte) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		c.Close(CloseTryAgainLater)
+		return false
+	}
+}
+
+// Close makes the writer send a close frame with code and stop; the read loop then fails
+func (c *Client) Close(code int) {
+	c.kickOnce.Do(func() { c.kick <- code })
+}
+
+func (c *Client) writeLoop() {
+	defer close(c.done)
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.Conn.WriteMessage(OpText, msg); err != nil {
+				c.Conn.Close()
+				return
+			}
+		case <-ping.C:
+			if err := c.Conn.WritePing(); err != nil {
+				c.Conn.Close()
+				return
+			}
+		case code := <-c.kick:
+			reason := ""
+			if code == CloseTryAgainLater {
+				reason = "too slow, reconnect"
+			}
+			c.Conn.WriteClose(code, reason)
+			// give the peer a moment to answer the close, then unblock the reader
+			c.Conn.SetReadDeadline(time.Now().Add(time.Second))
+			return
+		}
+	}
+}
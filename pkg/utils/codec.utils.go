@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// supported media types (aliases map onto the first one of their group)
+const (
+	MediaJSON    = "application/json"
+	MediaXML     = "application/xml"
+	MediaCSV     = "text/csv"
+	MediaMsgpack = "application/msgpack"
+)
+
+var mediaAliases = map[string]string{
+	"application/json":        MediaJSON,
+	"application/xml":         MediaXML,
+	"text/xml":                MediaXML,
+	"text/csv":                MediaCSV,
+	"application/msgpack":     MediaMsgpack,
+	"application/x-msgpack":   MediaMsgpack,
+	"application/vnd.msgpack": MediaMsgpack,
+}
+
+// NormalizeMediaType maps a Content-Type/Accept value onto one of the Media* constants ("" if unsupported)
+func NormalizeMediaType(value string) string {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return ""
+	}
+	return mediaAliases[mediaType]
+}
+
+// ErrNotRepresentable - the payload has no sensible form in the requested media type (e.g. CSV of a single object)
+var ErrNotRepresentable = errors.New("payload can't be represented in this media type")
+
+// Negotiate returns the supported media types the Accept header allows, best first.
+// An empty Accept means "anything", so JSON comes first.
+func Negotiate(accept string, offers []string) []string {
+	if strings.TrimSpace(accept) == "" {
+		return offers
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if alias, ok := mediaAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+
+	// the most specific range decides an offer's q ("text/csv;q=0, */*" excludes csv)
+	quality := func(offer string) float64 {
+		best, specificity := 0.0, -1
+		for _, rg := range ranges {
+			s := -1
+			switch {
+			case rg.mediaType == offer:
+				s = 2
+			case strings.HasSuffix(rg.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(rg.mediaType, "*")):
+				s = 1
+			case rg.mediaType == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				best, specificity = rg.q, s
+			}
+		}
+		return best
+	}
+
+	var accepted []string
+	q := map[string]float64{}
+	for _, offer := range offers {
+		if q[offer] = quality(offer); q[offer] > 0 {
+			accepted = append(accepted, offer)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return q[accepted[i]] > q[accepted[j]] })
+	return accepted
+}
+
+//! Ordered JSON tree - encoding/json's map[string]any would shuffle the fields
+
+// Object keeps the keys of a JSON object in their original order
+type Object struct {
+	Keys   []string
+	Values map[string]any
+}
+
+// ParseJSON decodes into nil, bool, json.Number, string, []any or *Object
+func ParseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func parseJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &Object{Values: map[string]any{}}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			val, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.Values[key]; !dup {
+				obj.Keys = append(obj.Keys, key)
+			}
+			obj.Values[key] = val
+		}
+		_, err := dec.Token() // '}'
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			val, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err := dec.Token() // ']'
+		return list, err
+	}
+	return tok, nil
+}
+
+//! XML encoding - objects become elements, list entries <item>, the document root is <response>
+
+func EncodeXML(w io.Writer, v any) error {
+	enc := xml.NewEncoder(w)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := encodeXMLValue(enc, "response", v); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func encodeXMLValue(enc *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *Object:
+		for _, key := range v.Keys {
+			if err := encodeXMLValue(enc, key, v.Values[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := encodeXMLValue(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName turns a JSON key into a valid element name ("student count" -> "student_count", "1st" -> "_1st")
+func xmlName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		case i == 0 && unicode.IsDigit(r):
+			b.WriteRune('_')
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+//! CSV encoding - only lists: a top-level array or the "data" array of a {status, count, data} envelope
+
+func EncodeCSV(w io.Writer, v any) error {
+	if obj, ok := v.(*Object); ok {
+		v = obj.Values["data"]
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return ErrNotRepresentable
+	}
+
+	// columns = all keys, in the order they first show up
+	var columns []string
+	seen := map[string]bool{}
+	for _, item := range list {
+		obj, ok := item.(*Object)
+		if !ok {
+			columns = []string{"value"}
+			break
+		}
+		for _, key := range obj.Keys {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if columns != nil {
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, item := range list {
+		record := make([]string, len(columns))
+		obj, isObj := item.(*Object)
+		for i, column := range columns {
+			if isObj {
+				record[i] = csvCell(obj.Values[column])
+			} else {
+				record[i] = csvCell(item)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// nested values end up as JSON inside the cell
+func csvCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case *Object, []any:
+		var buf bytes.Buffer
+		writeJSON(&buf, v)
+		return buf.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// MarshalJSON keeps the key order
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	writeJSON(&buf, o)
+	return buf.Bytes(), nil
+}
+
+// writeJSON re-encodes a parsed tree, keeping the key order
+func writeJSON(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case *Object:
+		buf.WriteByte('{')
+		for i, key := range v.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			buf.Write(k)
+			buf.WriteByte(':')
+			writeJSON(buf, v.Values[key])
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, item)
+		}
+		buf.WriteByte(']')
+	default:
+		b, _ := json.Marshal(v)
+		buf.Write(b)
+	}
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const maxBodySize = 10 << 20 // 10MB
+
+// DecodeBody decodes the request body into v following its Content-Type (JSON when there's none).
+// XML and MessagePack are converted to JSON first, so the json-tags of the models apply to all of them.
+func DecodeBody(r *http.Request, v any) error {
+	contentType := r.Header.Get("Content-Type")
+	mediaType := MediaJSON
+	if contentType != "" {
+		mediaType = NormalizeMediaType(contentType)
+	}
+
+	switch mediaType {
+	case MediaJSON:
+		return json.NewDecoder(r.Body).Decode(v)
+	case MediaXML, MediaMsgpack:
+	default:
+		return fmt.Errorf("unsupported Content-Type %q", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	var generic any
+	if mediaType == MediaMsgpack {
+		generic, err = DecodeMsgpack(data)
+	} else {
+		generic, err = decodeXMLFor(data, reflect.TypeOf(v).Elem())
+	}
+	if err != nil {
+		return err
+	}
+	asJSON, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(asJSON, v)
+}
+
+// FoldKey - encoding/json matches object keys to fields case-insensitively ("EMAIL" fills Email too),
+// so two keys are the same parameter when their FoldKeys are equal
+func FoldKey(key string) string {
+	return strings.ToLower(strings.ToUpper(key))
+}
+
+// keySet notes the keys of one object/element and reports the repeated ones
+type keySet map[string]bool
+
+func (s keySet) repeated(key string) bool {
+	folded := FoldKey(key)
+	if s[folded] {
+		return true
+	}
+	s[folded] = true
+	return false
+}
+
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: tok.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root != nil {
+				return nil, fmt.Errorf("xml: more than one root element")
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("xml: empty document")
+	}
+	return root, nil
+}
+
+// decodeXMLFor converts an XML document into JSON-able values shaped after the target type -
+// XML has no types of its own, so <id>7</id> only becomes a number where the model wants one.
+func decodeXMLFor(data []byte, target reflect.Type) (any, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	return xmlValue(root, target)
+}
+
+func xmlValue(node *xmlNode, t reflect.Type) (any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	text := strings.TrimSpace(node.text)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj := map[string]any{}
+		seen := keySet{}
+		for _, child := range node.children {
+			field, ok := jsonField(t, child.name)
+			if !ok {
+				continue // like encoding/json: unknown fields are ignored
+			}
+			if seen.repeated(child.name) {
+				return nil, fmt.Errorf("xml: <%s> is repeated in <%s>", child.name, node.name)
+			}
+			v, err := xmlValue(child, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			obj[child.name] = v
+		}
+		return obj, nil
+	case reflect.Map:
+		obj := map[string]any{}
+		seen := keySet{}
+		for _, child := range node.children {
+			if seen.repeated(child.name) {
+				return nil, fmt.Errorf("xml: <%s> is repeated in <%s>", child.name, node.name)
+			}
+			v, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			obj[child.name] = v
+		}
+		return obj, nil
+	case reflect.Slice, reflect.Array:
+		list := []any{}
+		for _, child := range node.children {
+			v, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case reflect.String:
+		return text, nil
+	case reflect.Bool:
+		return strconv.ParseBool(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("xml: <%s> %q is not a number", node.name, text)
+		}
+		return json.Number(text), nil
+	case reflect.Interface:
+		return xmlGuess(node)
+	}
+	return nil, fmt.Errorf("xml: can't decode <%s>", node.name)
+}
+
+// xmlGuess is used for untyped targets (PATCH bodies): nested elements become objects
+// (or lists, if they're all <item>), text stays a string - "0123" or "5551234" may well be
+// a phone number, so the model's field type decides (ApplyUpdates converts it)
+func xmlGuess(node *xmlNode) (any, error) {
+	if len(node.children) > 0 {
+		allItems := true
+		for _, child := range node.children {
+			allItems = allItems && child.name == "item"
+		}
+		if allItems {
+			list := make([]any, len(node.children))
+			for i, child := range node.children {
+				v, err := xmlGuess(child)
+				if err != nil {
+					return nil, err
+				}
+				list[i] = v
+			}
+			return list, nil
+		}
+		obj := map[string]any{}
+		seen := keySet{}
+		for _, child := range node.children {
+			if seen.repeated(child.name) {
+				return nil, fmt.Errorf("xml: <%s> is repeated in <%s>", child.name, node.name)
+			}
+			v, err := xmlGuess(child)
+			if err != nil {
+				return nil, err
+			}
+			obj[child.name] = v
+		}
+		return obj, nil
+	}
+
+	return strings.TrimSpace(node.text), nil
+}
+
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
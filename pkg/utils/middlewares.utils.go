@@ -4,9 +4,10 @@ import "net/http"
 
 type Middleware func(http.Handler) http.Handler
 
+// ApplyMiddlewares - the first middleware is the outermost: it sees the request first and the response last
 func ApplyMiddlewares(handler http.Handler, middlewares ...Middleware) http.Handler {
-	for _, mw := range middlewares {
-		handler = mw(handler)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
 	}
 	return handler
 }
\ No newline at end of file
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Minimal MessagePack (https://msgpack.org/) - just the types a JSON document can hold.
+
+// EncodeMsgpack writes a tree from ParseJSON (or plain map/slice values) as MessagePack
+func EncodeMsgpack(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	if err := encodeMsgpackValue(&buf, v); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encodeMsgpackValue(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			writeMsgpackInt(buf, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		writeMsgpackFloat(buf, f)
+	case int:
+		writeMsgpackInt(buf, int64(v))
+	case int64:
+		writeMsgpackInt(buf, v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			writeMsgpackInt(buf, int64(v))
+		} else {
+			writeMsgpackFloat(buf, v)
+		}
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []any:
+		writeMsgpackHeader(buf, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMsgpackValue(buf, item); err != nil {
+				return err
+			}
+		}
+	case *Object:
+		writeMsgpackHeader(buf, len(v.Keys), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range v.Keys {
+			encodeMsgpackValue(buf, key)
+			if err := encodeMsgpackValue(buf, v.Values[key]); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		writeMsgpackHeader(buf, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for key, val := range v {
+			encodeMsgpackValue(buf, key)
+			if err := encodeMsgpackValue(buf, val); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+// writeMsgpackHeader writes the fix-/8-/16-/32-bit length prefix of a str, array or map (code8 == 0: no 8-bit form)
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 127:
+		buf.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeMsgpackFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, f)
+}
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+// DecodeMsgpack reads one MessagePack value into nil, bool, int64, uint64, float64, string, []any or map[string]any
+func DecodeMsgpack(data []byte) (any, error) {
+	r := bytes.NewReader(data)
+	v, err := decodeMsgpackValue(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("msgpack: unexpected data after value")
+	}
+	return v, nil
+}
+
+const maxMsgpackDepth = 64
+
+func decodeMsgpackValue(r *bytes.Reader, depth int) (any, error) {
+	if depth > maxMsgpackDepth {
+		return nil, fmt.Errorf("msgpack: nested too deep")
+	}
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, errMsgpackShort
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code >= 0xa0 && code <= 0xbf:
+		return readMsgpackString(r, int(code&0x1f))
+	case code >= 0x90 && code <= 0x9f:
+		return readMsgpackArray(r, int(code&0x0f), depth)
+	case code >= 0x80 && code <= 0x8f:
+		return readMsgpackMap(r, int(code&0x0f), depth)
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readMsgpackUint(r, 1<<(code-0xcc))
+		return n, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := readMsgpackUint(r, size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil // sign-extend
+	case 0xca:
+		n, err := readMsgpackUint(r, 4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readMsgpackUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackUint(r, 1<<(code-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readMsgpackUint(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, int(n), depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackUint(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, int(n), depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%x", code)
+}
+
+func readMsgpackUint(r *bytes.Reader, size int) (uint64, error) {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b[8-size:]); err != nil {
+		return 0, errMsgpackShort
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func readMsgpackString(r *bytes.Reader, n int) (string, error) {
+	if n > r.Len() {
+		return "", errMsgpackShort
+	}
+	b := make([]byte, n)
+	io.ReadFull(r, b)
+	return string(b), nil
+}
+
+func readMsgpackArray(r *bytes.Reader, n int, depth int) ([]any, error) {
+	if n > r.Len() { // every element takes at least a byte
+		return nil, errMsgpackShort
+	}
+	list := make([]any, n)
+	for i := range list {
+		v, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func readMsgpackMap(r *bytes.Reader, n int, depth int) (map[string]any, error) {
+	if 2*n > r.Len() {
+		return nil, errMsgpackShort
+	}
+	m := make(map[string]any, n)
+	seen := keySet{}
+	for range n {
+		key, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map keys must be strings")
+		}
+		// a repeated key would be resolved silently (and differently than the first one was validated)
+		if seen.repeated(k) {
+			return nil, fmt.Errorf("msgpack: duplicate map key %q", k)
+		}
+		v, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,21 @@
+-- Classes become a first-class resource.
+-- teachers.class / students.class keep holding the class *name* ("9B"),
+-- the API validates them against this table on every write.
+
+CREATE TABLE IF NOT EXISTS classes (
+    id                  INT AUTO_INCREMENT PRIMARY KEY,
+    name                VARCHAR(50)  NOT NULL UNIQUE,
+    grade               INT          NOT NULL,
+    section             VARCHAR(10)  NOT NULL,
+    homeroom_teacher_id INT          NULL,
+    capacity            INT          NOT NULL DEFAULT 0,
+    FOREIGN KEY (homeroom_teacher_id) REFERENCES teachers(id) ON DELETE SET NULL
+);
+
+-- seed the table from the free-text values already in use
+INSERT IGNORE INTO classes (name, grade, section)
+SELECT DISTINCT class,
+       CAST(REGEXP_SUBSTR(class, '^[0-9]+') AS UNSIGNED),
+       REGEXP_REPLACE(class, '^[0-9]+', '')
+FROM (SELECT class FROM teachers UNION SELECT class FROM students) AS existing
+WHERE class IS NOT NULL AND class <> '';
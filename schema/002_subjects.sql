@@ -0,0 +1,20 @@
+-- Subjects catalogue + which teacher teaches which subject to which class.
+-- teachers.subject stays as the teacher's primary subject, the `subject`
+-- filter on /teachers matches it OR any assignment.
+
+CREATE TABLE IF NOT EXISTS subjects (
+    id   INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    code VARCHAR(20)  NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS teacher_subject_class (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    teacher_id INT NOT NULL,
+    subject_id INT NOT NULL,
+    class_id   INT NOT NULL,
+    UNIQUE KEY uq_assignment (teacher_id, subject_id, class_id),
+    FOREIGN KEY (teacher_id) REFERENCES teachers(id) ON DELETE CASCADE,
+    FOREIGN KEY (subject_id) REFERENCES subjects(id) ON DELETE RESTRICT,
+    FOREIGN KEY (class_id)   REFERENCES classes(id)  ON DELETE RESTRICT
+);
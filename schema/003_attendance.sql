@@ -0,0 +1,17 @@
+-- Daily attendance, one row per student per date per period.
+
+CREATE TABLE IF NOT EXISTS attendance (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT  NOT NULL,
+    class_id   INT  NOT NULL,
+    date       DATE NOT NULL,
+    period     INT  NOT NULL DEFAULT 1,
+    status     ENUM('present', 'absent', 'late', 'excused') NOT NULL,
+    marked_by  INT  NULL,
+    note       VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE KEY uq_attendance (student_id, date, period),
+    KEY idx_attendance_class_date (class_id, date),
+    FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+    FOREIGN KEY (class_id)   REFERENCES classes(id)  ON DELETE RESTRICT,
+    FOREIGN KEY (marked_by)  REFERENCES teachers(id) ON DELETE SET NULL
+);
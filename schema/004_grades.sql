@@ -0,0 +1,28 @@
+-- Assessments (exam / quiz / assignment) and the per-student scores entered by teachers.
+
+CREATE TABLE IF NOT EXISTS assessments (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    title      VARCHAR(150) NOT NULL,
+    type       ENUM('exam', 'quiz', 'assignment') NOT NULL,
+    subject_id INT          NOT NULL,
+    class_id   INT          NOT NULL,
+    teacher_id INT          NULL,
+    max_score  DECIMAL(6,2) NOT NULL,
+    date       DATE         NOT NULL,
+    term       VARCHAR(20)  NOT NULL,
+    KEY idx_assessments_class_term (class_id, term),
+    FOREIGN KEY (subject_id) REFERENCES subjects(id) ON DELETE RESTRICT,
+    FOREIGN KEY (class_id)   REFERENCES classes(id)  ON DELETE RESTRICT,
+    FOREIGN KEY (teacher_id) REFERENCES teachers(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+    id            INT AUTO_INCREMENT PRIMARY KEY,
+    assessment_id INT          NOT NULL,
+    student_id    INT          NOT NULL,
+    score         DECIMAL(6,2) NOT NULL,
+    remarks       VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE KEY uq_score (assessment_id, student_id),
+    FOREIGN KEY (assessment_id) REFERENCES assessments(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id)    REFERENCES students(id)    ON DELETE CASCADE
+);
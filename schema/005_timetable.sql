@@ -0,0 +1,30 @@
+-- Weekly timetable. day: 1 = Monday ... 7 = Sunday (ISO weekday).
+-- The API reports clashes with a 409 before these UNIQUE keys ever fire,
+-- they're only the last line of defence against concurrent writers.
+
+CREATE TABLE IF NOT EXISTS periods (
+    period     INT  PRIMARY KEY,
+    start_time TIME NOT NULL,
+    end_time   TIME NOT NULL
+);
+
+INSERT IGNORE INTO periods (period, start_time, end_time) VALUES
+    (1, '08:00', '08:45'), (2, '08:50', '09:35'), (3, '09:40', '10:25'), (4, '10:45', '11:30'),
+    (5, '11:35', '12:20'), (6, '13:00', '13:45'), (7, '13:50', '14:35'), (8, '14:40', '15:25');
+
+CREATE TABLE IF NOT EXISTS timetable_slots (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    day        TINYINT     NOT NULL,
+    period     INT         NOT NULL,
+    class_id   INT         NOT NULL,
+    subject_id INT         NOT NULL,
+    teacher_id INT         NOT NULL,
+    room       VARCHAR(50) NOT NULL,
+    UNIQUE KEY uq_slot_teacher (day, period, teacher_id),
+    UNIQUE KEY uq_slot_class   (day, period, class_id),
+    UNIQUE KEY uq_slot_room    (day, period, room),
+    FOREIGN KEY (period)     REFERENCES periods(period)  ON DELETE RESTRICT,
+    FOREIGN KEY (class_id)   REFERENCES classes(id)      ON DELETE CASCADE,
+    FOREIGN KEY (subject_id) REFERENCES subjects(id)     ON DELETE RESTRICT,
+    FOREIGN KEY (teacher_id) REFERENCES teachers(id)     ON DELETE CASCADE
+);
@@ -0,0 +1,40 @@
+-- Academic years/terms and the enrollment history of every student.
+-- students.class stays as the student's *current* class, enrollments keep the history.
+
+CREATE TABLE IF NOT EXISTS academic_years (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(20) NOT NULL UNIQUE,  -- "2026/27"
+    start_date DATE        NOT NULL,
+    end_date   DATE        NOT NULL,
+    is_current BOOLEAN     NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS terms (
+    id               INT AUTO_INCREMENT PRIMARY KEY,
+    academic_year_id INT         NOT NULL,
+    name             VARCHAR(20) NOT NULL,  -- "T1"
+    start_date       DATE        NOT NULL,
+    end_date         DATE        NOT NULL,
+    UNIQUE KEY uq_term (academic_year_id, name),
+    FOREIGN KEY (academic_year_id) REFERENCES academic_years(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    id               INT AUTO_INCREMENT PRIMARY KEY,
+    student_id       INT  NOT NULL,
+    class_id         INT  NOT NULL,
+    academic_year_id INT  NULL,
+    start_date       DATE NOT NULL,
+    end_date         DATE NULL,  -- NULL => current enrollment
+    reason           ENUM('enrolled', 'transfer', 'promotion') NOT NULL DEFAULT 'enrolled',
+    KEY idx_enrollments_student (student_id, end_date),
+    FOREIGN KEY (student_id)       REFERENCES students(id)       ON DELETE CASCADE,
+    FOREIGN KEY (class_id)         REFERENCES classes(id)        ON DELETE RESTRICT,
+    FOREIGN KEY (academic_year_id) REFERENCES academic_years(id) ON DELETE SET NULL
+);
+
+-- open an enrollment for every student that's already sitting in a class
+INSERT INTO enrollments (student_id, class_id, academic_year_id, start_date)
+SELECT s.id, c.id, (SELECT id FROM academic_years WHERE is_current LIMIT 1), CURDATE()
+FROM students s JOIN classes c ON c.name = s.class
+WHERE NOT EXISTS (SELECT 1 FROM enrollments e WHERE e.student_id = s.id AND e.end_date IS NULL);
@@ -0,0 +1,22 @@
+-- Guardians / parents and which students they are responsible for.
+-- A guardian can have several children in the school and a student can
+-- have several guardians, so the link lives in student_guardians.
+
+CREATE TABLE IF NOT EXISTS guardians (
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    last_name  VARCHAR(255) NOT NULL,
+    relation   VARCHAR(20)  NOT NULL,
+    phone      VARCHAR(30)  NOT NULL DEFAULT '',
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    is_primary BOOLEAN      NOT NULL DEFAULT FALSE,
+    INDEX idx_guardians_email (email)
+);
+
+CREATE TABLE IF NOT EXISTS student_guardians (
+    student_id  INT NOT NULL,
+    guardian_id INT NOT NULL,
+    PRIMARY KEY (student_id, guardian_id),
+    FOREIGN KEY (student_id)  REFERENCES students(id)  ON DELETE CASCADE,
+    FOREIGN KEY (guardian_id) REFERENCES guardians(id) ON DELETE CASCADE
+);
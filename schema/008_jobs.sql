@@ -0,0 +1,24 @@
+-- Background jobs (imports, exports, promotions). Workers claim queued jobs with
+-- SELECT ... FOR UPDATE SKIP LOCKED, so several API replicas can share the table.
+-- A running job keeps heartbeat_at fresh; a stale heartbeat means its worker died
+-- and the job goes back to the queue.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id               INT AUTO_INCREMENT PRIMARY KEY,
+    type             VARCHAR(50) NOT NULL,
+    status           ENUM('queued', 'running', 'succeeded', 'failed', 'cancelled') NOT NULL DEFAULT 'queued',
+    payload          LONGTEXT    NOT NULL,
+    result           LONGTEXT    NULL,
+    error            TEXT        NULL,
+    done             INT         NOT NULL DEFAULT 0,
+    total            INT         NOT NULL DEFAULT 0,
+    attempts         INT         NOT NULL DEFAULT 0,
+    max_attempts     INT         NOT NULL DEFAULT 3,
+    cancel_requested BOOLEAN     NOT NULL DEFAULT FALSE,
+    run_after        DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    heartbeat_at     DATETIME    NULL,
+    created_at       DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at       DATETIME    NULL,
+    finished_at      DATETIME    NULL,
+    KEY idx_jobs_queue (status, run_after)
+);
@@ -0,0 +1,46 @@
+-- Webhook subscriptions and their delivery log. events is a comma separated
+-- filter ("teacher.created,student.*", "*" for everything). Every matching event
+-- becomes a row in webhook_deliveries; the attempts themselves run as background
+-- jobs, which take care of retrying with backoff.
+--
+-- Deliveries of one aggregate (teacher 7, student 3) to a webhook go out one after
+-- the other: a new one is 'waiting' while an earlier one is still pending, and the
+-- oldest waiting one is started (its job queued) when that finishes. job_id is the
+-- job of the latest attempt - a pending delivery whose job ended without recording
+-- a result is failed by the sweep, so it can't hold up the ones behind it.
+-- An event is delivered to a webhook once: the outbox may send it again.
+-- Only the receiver's response status is kept, never its body (a webhook URL
+-- pointing at an internal service would make that readable through the API).
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          INT AUTO_INCREMENT PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    events      VARCHAR(1024) NOT NULL DEFAULT '*',
+    secret      VARCHAR(255)  NOT NULL,
+    description VARCHAR(255)  NOT NULL DEFAULT '',
+    active      BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at  DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id      INT          NOT NULL,
+    event_id        VARCHAR(64)  NOT NULL,
+    event           VARCHAR(50)  NOT NULL,
+    aggregate_type  VARCHAR(20)  NOT NULL DEFAULT '',
+    aggregate_id    INT          NOT NULL DEFAULT 0,
+    payload         LONGTEXT     NOT NULL,
+    status          ENUM('waiting', 'pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    job_id          INT          NULL,
+    attempts        INT          NOT NULL DEFAULT 0,
+    response_status INT          NULL,
+    error           TEXT         NULL,
+    duration_ms     INT          NULL,
+    created_at      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at DATETIME     NULL,
+    KEY idx_webhook_deliveries_webhook (webhook_id, id),
+    KEY idx_webhook_deliveries_order (webhook_id, aggregate_type, aggregate_id, status, id),
+    KEY idx_webhook_deliveries_status (status, job_id),
+    UNIQUE KEY uq_webhook_deliveries_event (webhook_id, event_id),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+-- Transactional outbox. Every change to a teacher/student writes its domain
+-- event here in the same transaction, so an event exists if and only if the
+-- change was committed. The dispatcher relays undispatched rows to the sinks
+-- (at-least-once) and only ever takes the oldest pending event of an aggregate,
+-- which keeps the events of one teacher/student in order.
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_id        VARCHAR(64) NOT NULL,
+    type            VARCHAR(50) NOT NULL,
+    aggregate_type  VARCHAR(30) NOT NULL,
+    aggregate_id    INT         NOT NULL,
+    payload         LONGTEXT    NOT NULL,
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT        NULL,
+    next_attempt_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at      DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at   DATETIME    NULL,
+    UNIQUE KEY uq_outbox_event (event_id),
+    KEY idx_outbox_pending (dispatched_at, next_attempt_at),
+    KEY idx_outbox_aggregate (aggregate_type, aggregate_id, id)
+);
@@ -0,0 +1,13 @@
+-- Shared rate-limit counters, so every API replica enforces the same quota.
+-- One row per client/policy and fixed window; the limiter weighs the previous
+-- window's hits in (sliding-window counter). A row is only needed until its
+-- following window is over - expires_at - after that it's purged.
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket_key   VARCHAR(191) NOT NULL,
+    window_start BIGINT       NOT NULL, -- unix ms
+    hits         INT          NOT NULL DEFAULT 0,
+    expires_at   DATETIME     NOT NULL,
+    PRIMARY KEY (bucket_key, window_start),
+    KEY idx_rate_limits_expires (expires_at)
+);